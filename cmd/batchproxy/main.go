@@ -1,38 +1,20 @@
+// This binary is a thin shim over the "batchproxy" command in the
+// consolidated dayusch binary (cmd/dayusch), kept for one release so
+// existing invocations keep working. The old "-concurrent"/"-c",
+// "-batch"/"-b" and "-delay"/"-d" shorthand reconciliation is gone: the
+// shared command now declares "-c"/"-b"/"-d" as aliases directly.
 package main
 
 import (
-	"dayusch/internal/pkg/app/batchproxy"
-	"flag"
+	"fmt"
+	"os"
+
+	"dayusch/internal/pkg/commands"
 )
 
 func main() {
-	// Parse command line flags
-	var (
-		maxConcurrent = flag.Uint("concurrent", 10, "Number of concurrent workers")
-		concurrentC   = flag.Uint("c", 10, "Number of concurrent workers (shorthand)")
-		batchLimit    = flag.Uint("batch", 10, "Batch limit")
-		batchB        = flag.Uint("b", 10, "Batch limit (shorthand)")
-		delay         = flag.Uint("delay", 0, "Delay between rounds in seconds")
-		delayD        = flag.Uint("d", 0, "Delay between rounds in seconds (shorthand)")
-		inputFile     = flag.String("i", "", "Input YAML config file")
-	)
-	flag.Parse()
-
-	// Use shorthand flags if they were explicitly set
-	finalConcurrent := *maxConcurrent
-	if *concurrentC != 10 {
-		finalConcurrent = *concurrentC
-	}
-
-	finalBatch := *batchLimit
-	if *batchB != 10 {
-		finalBatch = *batchB
+	if err := commands.RunStandalone(commands.BatchProxyCommand(), os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-
-	finalDelay := *delay
-	if *delayD != 1 {
-		finalDelay = *delayD
-	}
-
-	batchproxy.Run(finalConcurrent, finalBatch, finalDelay, *inputFile)
 }
@@ -8,12 +8,13 @@ import (
 func main() {
 	// Parse command line flags
 	var (
-		concurrent = flag.Uint("c", 10, "Number of concurrent workers")
-		batch      = flag.Uint("b", 10, "Batch limit")
-		delay      = flag.Uint("d", 0, "Delay between rounds in seconds")
-		inputFile  = flag.String("i", "", "Input YAML config file")
+		concurrent    = flag.Uint("c", 10, "Number of concurrent workers")
+		batch         = flag.Uint("b", 10, "Batch limit")
+		delay         = flag.Uint("d", 0, "Delay between rounds in seconds")
+		inputFile     = flag.String("i", "", "Input YAML config file")
+		wireguardFile = flag.String("w", "", "WireGuard config file for tunneled egress (optional, coexists with the dataimpulse proxy)")
 	)
 	flag.Parse()
 
-	batchproxyplay.Run(*concurrent, *batch, *delay, *inputFile)
+	batchproxyplay.Run(*concurrent, *batch, *delay, *inputFile, *wireguardFile)
 }
@@ -0,0 +1,35 @@
+// Command dayusch consolidates the repo's standalone CLIs (batch,
+// batchproxy, check-domains, ipscan, schstat, statsworker) into a single
+// binary with a shared command tree. The old per-tool binaries under
+// cmd/ are kept as thin shims over the same commands for one release.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"dayusch/internal/pkg/commands"
+)
+
+func main() {
+	app := &cli.App{
+		Name:  "dayusch",
+		Usage: "dayu-sch batch claiming and monitoring toolkit",
+		Flags: commands.GlobalFlags(),
+		Commands: []*cli.Command{
+			commands.BatchCommand(),
+			commands.BatchProxyCommand(),
+			commands.CheckDomainsCommand(),
+			commands.IPScanCommand(),
+			commands.SchStatCommand(),
+			commands.StatsWorkerCommand(),
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"dayusch/internal/pkg/app/dupdel"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,6 +13,18 @@ import (
 )
 
 func main() {
+	var (
+		runName = flag.String("run", "default", "Run name, keys this run's checkpoint and lock in the _dupdel_state collection")
+		resume  = flag.Bool("resume", false, "Resume the named run from its last checkpoint (default behavior; accepted for explicitness)")
+		restart = flag.Bool("restart", false, "Discard any existing checkpoint for the named run and start over from scratch")
+	)
+	flag.Parse()
+
+	if *resume && *restart {
+		fmt.Fprintln(os.Stderr, "-resume and -restart are mutually exclusive")
+		os.Exit(1)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -24,6 +38,6 @@ func main() {
 		cancel()
 	}()
 
-	dupDel := dupdel.NewDupDel(ctx)
+	dupDel := dupdel.NewDupDel(ctx, *runName, *restart)
 	dupDel.Run()
 }
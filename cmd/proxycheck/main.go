@@ -4,14 +4,15 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"dayusch/internal/pkg/admin"
 	"dayusch/internal/pkg/app/proxycheck"
-
-	"github.com/charmbracelet/log"
+	"dayusch/internal/pkg/obs"
 )
 
 func main() {
@@ -22,6 +23,10 @@ func main() {
 		proxyPassword = flag.String("proxy-password", "", "Proxy password")
 		interval      = flag.Duration("interval", 5*time.Second, "Check interval")
 		limit         = flag.Int("limit", 32, "Limit of blocked proxies to check")
+		adminAddr     = flag.String("admin-addr", "", "Admin HTTP server listen address (disabled if empty)")
+		adminUsername = flag.String("admin-username", "", "Admin HTTP server basic auth username")
+		adminPassword = flag.String("admin-password", "", "Admin HTTP server basic auth password")
+		configPath    = flag.String("config", "", "Path to a YAML check config overriding the default test URLs and error patterns (uses the built-in defaults if empty)")
 	)
 	flag.Parse()
 
@@ -31,8 +36,21 @@ func main() {
 		os.Exit(1)
 	}
 
+	logger := obs.Default()
+
+	var opts []proxycheck.Option
+	if *configPath != "" {
+		checkConfig, err := proxycheck.LoadCheckConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load check config: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, proxycheck.WithCheckConfig(checkConfig))
+	}
+
 	// Create proxy checker
-	checker := proxycheck.NewProxyChecker(*yarunURL, *yarunToken, *proxyUsername, *proxyPassword, *limit)
+	checker := proxycheck.NewProxyChecker(*yarunURL, *yarunToken, *proxyUsername, *proxyPassword, *limit, opts...)
+	defer checker.Stop()
 
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -43,12 +61,36 @@ func main() {
 
 	go func() {
 		<-c
-		log.Info("Received shutdown signal, stopping...")
+		logger.Info(ctx, "Received shutdown signal, stopping...")
 		cancel()
 	}()
 
-	log.Info("Starting proxy checker", "interval", *interval, "limit", *limit)
-	log.Info("Yarun API configured", "url", *yarunURL)
+	logger.Info(ctx, "Starting proxy checker", "interval", *interval, "limit", *limit)
+	logger.Info(ctx, "Yarun API configured", "url", *yarunURL)
+
+	if *adminAddr != "" {
+		adminServer := admin.NewServer(*adminUsername, *adminPassword)
+		adminServer.Handle("/api/status", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			admin.WriteJSON(w, checker.Stats())
+		})
+
+		go func() {
+			logger.Info(ctx, "Starting proxycheck admin server", "addr", *adminAddr)
+			if err := adminServer.ListenAndServe(*adminAddr); err != nil && err != http.ErrServerClosed {
+				logger.Error(ctx, "Admin server stopped", "error", err)
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer shutdownCancel()
+			adminServer.Shutdown(shutdownCtx)
+		}()
+	}
 
 	// Start the checking loop
 	ticker := time.NewTicker(*interval)
@@ -56,18 +98,18 @@ func main() {
 
 	// Run initial check
 	if err := checker.CheckProxies(ctx); err != nil {
-		log.Error("Initial check failed", "error", err)
+		logger.Error(ctx, "Initial check failed", "error", err)
 	}
 
 	// Continue checking at intervals
 	for {
 		select {
 		case <-ctx.Done():
-			log.Info("Shutting down proxy checker")
+			logger.Info(ctx, "Shutting down proxy checker")
 			return
 		case <-ticker.C:
 			if err := checker.CheckProxies(ctx); err != nil {
-				log.Error("Check failed", "error", err)
+				logger.Error(ctx, "Check failed", "error", err)
 			}
 		}
 	}
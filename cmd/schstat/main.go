@@ -1,31 +1,18 @@
+// This binary is a thin shim over the "schstat" command in the
+// consolidated dayusch binary (cmd/dayusch), kept for one release so
+// existing invocations keep working.
 package main
 
 import (
-	"context"
-	"dayusch/internal/pkg/app/schstat"
+	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 
-	"github.com/charmbracelet/log"
+	"dayusch/internal/pkg/commands"
 )
 
 func main() {
-	ctxParent := context.Background()
-	ctx, cancel := context.WithCancel(ctxParent)
-	defer cancel()
-
-	app := schstat.NewSchStat(ctx)
-
-	go func() {
-		// Wait for interrupt signal to gracefully shutdown the application
-		sigs := make(chan os.Signal, 1)
-		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-		<-sigs
-
-		log.Info("shutting down...")
-		cancel()
-	}()
-
-	app.Run()
+	if err := commands.RunStandalone(commands.SchStatCommand(), os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
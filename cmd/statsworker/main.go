@@ -0,0 +1,18 @@
+// This binary is a thin shim over the "statsworker" command in the
+// consolidated dayusch binary (cmd/dayusch), kept for one release so
+// existing invocations keep working.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"dayusch/internal/pkg/commands"
+)
+
+func main() {
+	if err := commands.RunStandalone(commands.StatsWorkerCommand(), os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
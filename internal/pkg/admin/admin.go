@@ -0,0 +1,90 @@
+// Package admin provides a small basic-auth-protected HTTP server that
+// batchproxy, proxycheck, and dupdel each start alongside their main
+// Run loop, turning what used to be fire-and-forget CLIs into
+// observable services: a /api/status endpoint for point-in-time state,
+// (where the app supports it) a /api/reload endpoint to apply a config
+// change without restarting, and (via LogBus and HandleWS) live
+// WebSocket log/event tailing.
+//
+// Each app registers its own handlers via Handle/HandleWS; this
+// package only owns the listener, the auth check, and the fan-out, the
+// same division of labor as batchproxyplay's control.Server (which has
+// no auth requirement, so it doesn't need this wrapper).
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+)
+
+// Server is a basic-auth-protected HTTP server. Handlers registered
+// via Handle all require the same username/password.
+type Server struct {
+	username string
+	password string
+
+	mux        *http.ServeMux
+	httpServer *http.Server
+}
+
+// NewServer returns a Server requiring username/password on every
+// registered handler. An empty username disables auth entirely, so a
+// local/trusted deployment can skip configuring credentials.
+func NewServer(username, password string) *Server {
+	mux := http.NewServeMux()
+	return &Server{
+		username:   username,
+		password:   password,
+		mux:        mux,
+		httpServer: &http.Server{Handler: mux},
+	}
+}
+
+// Handle registers handler for pattern, wrapped in the basic-auth check.
+func (s *Server) Handle(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, s.requireAuth(handler))
+}
+
+func (s *Server) requireAuth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.username == "" {
+			handler(w, r)
+			return
+		}
+
+		user, pass, ok := r.BasicAuth()
+		userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(s.username)) == 1
+		passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(s.password)) == 1
+		if !ok || !userMatch || !passMatch {
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// ListenAndServe blocks serving on addr until the server is shut down.
+func (s *Server) ListenAndServe(addr string) error {
+	s.httpServer.Addr = addr
+	return s.httpServer.ListenAndServe()
+}
+
+// Shutdown gracefully stops the server, waiting out in-flight requests
+// until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+// WriteJSON encodes v as the JSON response body. Shared by every app's
+// handlers so they don't each redeclare the same three lines.
+func WriteJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warn("Failed to encode admin response", "error", err)
+	}
+}
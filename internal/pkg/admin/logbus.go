@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"sync"
+	"time"
+)
+
+// LogRecord is one line published to a LogBus: either a lifecycle
+// event (Type set, e.g. "worker_started") or a plain worker-tagged log
+// line (Type empty), so a single bus can back both a /ws/events stream
+// (events only) and a /ws/logs?worker=<id> stream (everything for one
+// worker) without duplicating the fan-out.
+type LogRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Type      string    `json:"type,omitempty"`
+	// WorkerID has no omitempty: worker IDs start at 0, so omitting the
+	// field for that worker would make its records indistinguishable
+	// from one with no worker at all to a client demuxing by this field.
+	WorkerID int            `json:"worker_id"`
+	Message  string         `json:"message"`
+	Fields   map[string]any `json:"fields,omitempty"`
+}
+
+// logBusBuffer is each subscriber's channel capacity. Past this, the
+// oldest buffered record is dropped to make room for the new one: a
+// live-tailing operator cares about what's happening now, not about
+// replaying everything they missed while behind.
+const logBusBuffer = 256
+
+// LogBus fans out LogRecords to any number of subscribers. Unlike
+// EventBus's publish, which drops the newest record for a full
+// subscriber, LogBus drops the oldest: a tailing client that falls
+// behind should see current activity resume, not stall forever behind
+// a backlog it'll never catch up on.
+type LogBus struct {
+	mu   sync.Mutex
+	subs map[chan LogRecord]struct{}
+}
+
+// NewLogBus returns an empty LogBus, ready to use.
+func NewLogBus() *LogBus {
+	return &LogBus{subs: make(map[chan LogRecord]struct{})}
+}
+
+// Subscribe registers a new channel and returns it along with an
+// unsubscribe func the caller must run once done reading from it.
+func (b *LogBus) Subscribe() (ch chan LogRecord, unsubscribe func()) {
+	ch = make(chan LogRecord, logBusBuffer)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// Publish delivers rec to every current subscriber. A subscriber whose
+// buffer is full has its oldest record dropped to make room for rec.
+func (b *LogBus) Publish(rec LogRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- rec:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- rec:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/gorilla/websocket"
+)
+
+// upgrader is shared by every HandleWS registration. Admin endpoints
+// are operator tooling reached behind basic auth, not browser pages
+// loaded from arbitrary origins, so the default same-origin check
+// would only get in the way of curl/wscat-style clients.
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// HandleWS registers a WebSocket endpoint at pattern, wrapped in the
+// same basic-auth check as Handle. stream is called once per
+// connection with the upgraded conn, and owns the connection until it
+// returns; HandleWS closes it afterward.
+func (s *Server) HandleWS(pattern string, stream func(conn *websocket.Conn, r *http.Request)) {
+	s.mux.HandleFunc(pattern, s.requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Warn("WebSocket upgrade failed", "error", err)
+			return
+		}
+		defer conn.Close()
+		stream(conn, r)
+	}))
+}
+
+// StreamLogRecords writes each LogRecord from ch to conn as JSON,
+// skipping any rec for which filter returns false (filter may be nil
+// to send everything), until ch closes, a write fails, or the client
+// disconnects. These streams are one-way (the client never sends
+// anything meaningful), but a connection still needs a read loop to
+// notice a closed/dropped client; without one, a client that vanishes
+// without a clean close would leave this goroutine blocked on ch
+// forever, leaking its LogBus subscription for the life of the process.
+func StreamLogRecords(conn *websocket.Conn, ch <-chan LogRecord, filter func(LogRecord) bool) {
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case rec, ok := <-ch:
+			if !ok {
+				return
+			}
+			if filter != nil && !filter(rec) {
+				continue
+			}
+			if err := conn.WriteJSON(rec); err != nil {
+				return
+			}
+		}
+	}
+}
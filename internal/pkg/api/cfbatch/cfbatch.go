@@ -4,25 +4,54 @@ import (
 	"context"
 	"net"
 
+	"dayusch/internal/pkg/auth"
+
 	"github.com/imroc/req/v3"
 )
 
 type CFBatchApi struct {
 	client *req.Client
+	auth   auth.Auth
 }
 
-func NewCFBatchApi(baseUrl, token string) *CFBatchApi {
+// NewCFBatchApi creates a new CFBatch API client. tokenDescriptor is an
+// auth.New descriptor ("htpasswd://file=...", "env://VAR", ...); a bare
+// token string still works via auth's static fallback, so existing
+// callers don't need to change.
+func NewCFBatchApi(baseUrl, tokenDescriptor string) *CFBatchApi {
+	a, err := auth.New(tokenDescriptor)
+	if err != nil {
+		// NewCFBatchApi has no error return, so a malformed descriptor
+		// falls back to a client that fails every request's Header()
+		// call with that same error, instead of panicking at startup.
+		a = auth.Failing(err)
+	}
+
 	client := req.C().
-		SetCommonHeader("x-token", token).
 		SetBaseURL(baseUrl)
 
+	client.OnBeforeRequest(func(c *req.Client, r *req.Request) error {
+		header, err := a.Header(r.Context())
+		if err != nil {
+			return err
+		}
+		r.SetHeader("x-token", header)
+		return nil
+	})
+
 	client.DevMode()
 
 	return &CFBatchApi{
 		client: client,
+		auth:   a,
 	}
 }
 
+// Stop releases the client's auth backend (e.g. a file watcher).
+func (a *CFBatchApi) Stop() {
+	a.auth.Stop()
+}
+
 func (a *CFBatchApi) SetDialContext(dialContext func(ctx context.Context, network string, addr string) (net.Conn, error)) {
 	a.client.DialContext = dialContext
 }
@@ -3,12 +3,20 @@ package cfbatch_v2
 import (
 	"context"
 	"fmt"
+	"net"
+	"time"
+
+	"dayusch/internal/pkg/apiclient"
+	"dayusch/internal/pkg/auth"
+	"dayusch/internal/pkg/obs"
 
 	"github.com/imroc/req/v3"
 )
 
 type CFBatchApi struct {
 	client *req.Client
+	logger *obs.Logger
+	auth   auth.Auth
 }
 
 type BatchResult struct {
@@ -23,31 +31,79 @@ type BatchResponse struct {
 	Result   BatchResult `json:"r"` // r = result
 }
 
-func NewCFBatchApi(baseUrl, token string) *CFBatchApi {
+// NewCFBatchApi creates a new CFBatch API client. tokenDescriptor is an
+// auth.New descriptor ("htpasswd://file=...", "env://VAR", ...); a bare
+// token string still works via auth's static fallback, so existing
+// callers don't need to change.
+func NewCFBatchApi(baseUrl, tokenDescriptor string) *CFBatchApi {
+	a, err := auth.New(tokenDescriptor)
+	if err != nil {
+		// NewCFBatchApi has no error return, so a malformed descriptor
+		// falls back to a client that fails every request's Header()
+		// call with that same error, instead of panicking at startup.
+		a = auth.Failing(err)
+	}
+
 	client := req.C().
-		SetCommonHeader("x-token", token).
 		SetCommonHeader("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36").
 		SetBaseURL(baseUrl)
 
+	client.OnBeforeRequest(func(c *req.Client, r *req.Request) error {
+		header, err := a.Header(r.Context())
+		if err != nil {
+			return err
+		}
+		r.SetHeader("x-token", header)
+		return nil
+	})
+
 	// client.DevMode()
 
+	logger := obs.Default()
+	obs.AttachHTTPDebugLogging(client, logger)
+	apiclient.Attach(client, apiclient.DefaultConfig())
+
 	return &CFBatchApi{
 		client: client,
+		logger: logger,
+		auth:   a,
 	}
 }
 
+// Clone returns a copy of a for use by a single worker goroutine. The
+// underlying auth backend (and any background file watcher it runs) is
+// shared, not re-parsed, since Clone is called once per worker per round.
 func (a *CFBatchApi) Clone() *CFBatchApi {
 	return &CFBatchApi{
 		client: a.client.Clone(),
+		logger: a.logger,
+		auth:   a.auth,
 	}
 }
 
+// Stop releases the client's auth backend (e.g. a file watcher). Only the
+// original, un-cloned CFBatchApi should call this, since clones share the
+// same auth instance.
+func (a *CFBatchApi) Stop() {
+	a.auth.Stop()
+}
+
 func (a *CFBatchApi) SetProxyURL(proxyURL string) {
 	a.client.SetProxyURL(proxyURL)
 }
 
+// SetDialContext overrides the low-level dialer used to establish the TCP
+// connection, e.g. to route it over a WireGuard tunnel. It composes with
+// SetProxyURL: DialContext controls what the HTTP CONNECT to the proxy
+// dials over, so a worker can tunnel over WireGuard first and then
+// HTTP-proxy through whatever SetProxyURL points at.
+func (a *CFBatchApi) SetDialContext(dialContext func(ctx context.Context, network, addr string) (net.Conn, error)) {
+	a.client.DialContext = dialContext
+}
+
 func (a *CFBatchApi) SendBatch(ctx context.Context, limit int) ([]BatchResponse, error) {
 	var response []BatchResponse
+	start := time.Now()
 
 	resp, err := a.client.R().
 		SetContext(ctx).
@@ -55,12 +111,15 @@ func (a *CFBatchApi) SendBatch(ctx context.Context, limit int) ([]BatchResponse,
 		SetSuccessResult(&response).
 		Post("/batch")
 	if err != nil {
+		a.logger.APICall(ctx, "POST /batch", time.Since(start), "error")
 		return nil, err
 	}
 
 	if !resp.IsSuccessState() {
+		a.logger.APICall(ctx, "POST /batch", time.Since(start), resp.Status)
 		return nil, fmt.Errorf("API request failed with status: %s", resp.Status)
 	}
 
+	a.logger.APICall(ctx, "POST /batch", time.Since(start), resp.Status, "batch_count", len(response))
 	return response, nil
 }
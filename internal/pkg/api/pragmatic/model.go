@@ -0,0 +1,35 @@
+package pragmatic
+
+import "fmt"
+
+// SpinResult is the decoded state returned by doInit/doSpin/doCollect on the
+// PragmaticPlay gameService endpoint.
+type SpinResult struct {
+	Index      int     `json:"index"`
+	Counter    int     `json:"counter"`
+	Balance    float64 `json:"balance"`
+	TotalWin   float64 `json:"tw"`
+	NextAction string  `json:"na"` // s = spin, c = collect, e = error
+	ScreenData string  `json:"sc"` // reel outcome layout
+	SymbolsBet string  `json:"sb"` // symbols in play for the current bet
+	BonusAward float64 `json:"ba"` // bonus trigger award
+	WinBonus   float64 `json:"wb"` // win-bonus amount
+	FreeSpins  int     `json:"fs"` // free spins remaining
+	Jackpot    bool    `json:"jp"` // jackpot flag
+	Attempts   int     `json:"-"`  // number of HTTP attempts made to obtain this result
+}
+
+// PragmaticError is returned when the response body carries `err=` or
+// `na=e`, surfacing the upstream error code instead of a zero-valued
+// SpinResult.
+type PragmaticError struct {
+	Code    string
+	Message string
+}
+
+func (e *PragmaticError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("pragmatic: %s (err=%s)", e.Message, e.Code)
+	}
+	return fmt.Sprintf("pragmatic: err=%s", e.Code)
+}
@@ -0,0 +1,72 @@
+package pragmatic
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// tokenize parses the `key=value&key2=value2` grammar used by the
+// PragmaticPlay gameService endpoint into a flat map. Values are
+// URL-decoded; pairs missing a `=` are skipped rather than failing the
+// whole parse, since the upstream response can carry trailing junk.
+func tokenize(body string) map[string]string {
+	values := make(map[string]string)
+
+	for _, pair := range strings.Split(body, "&") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+
+		v, err := url.QueryUnescape(kv[1])
+		if err != nil {
+			v = kv[1]
+		}
+		values[kv[0]] = v
+	}
+
+	return values
+}
+
+// ParseSpinResult decodes a raw doInit/doSpin/doCollect response body into a
+// SpinResult. It is exported so callers other than PragmaticPlay (batch,
+// batchproxy) can consume the same schema without re-implementing the
+// tokenizer.
+func ParseSpinResult(body string) (*SpinResult, error) {
+	values := tokenize(body)
+
+	if code := values["err"]; code != "" {
+		return nil, &PragmaticError{Code: code, Message: values["errMsg"]}
+	}
+
+	na := values["na"]
+	if na == "e" {
+		return nil, &PragmaticError{Code: "e", Message: values["msg"]}
+	}
+
+	getInt := func(key string) int {
+		v, _ := strconv.Atoi(values[key])
+		return v
+	}
+
+	getFloat := func(key string) float64 {
+		clean := strings.ReplaceAll(values[key], ",", "")
+		v, _ := strconv.ParseFloat(clean, 64)
+		return v
+	}
+
+	return &SpinResult{
+		Index:      getInt("index"),
+		Counter:    getInt("counter"),
+		Balance:    getFloat("balance"),
+		TotalWin:   getFloat("tw"),
+		NextAction: na,
+		ScreenData: values["sc"],
+		SymbolsBet: values["sb"],
+		BonusAward: getFloat("ba"),
+		WinBonus:   getFloat("wb"),
+		FreeSpins:  getInt("fs"),
+		Jackpot:    values["jp"] == "1",
+	}, nil
+}
@@ -3,8 +3,8 @@ package pragmatic
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/url"
-	"regexp"
 	"strconv"
 	"time"
 
@@ -12,22 +12,16 @@ import (
 )
 
 type PragmaticPlay struct {
-	ctx    context.Context
-	client *req.Client
-	url    *string
+	ctx         context.Context
+	client      *req.Client
+	url         *string
+	retryPolicy *RetryPolicy
 }
 
 type SessionData struct {
 	RedirectURL string
 	MGCKey      string
-}
-
-type ResponseData struct {
-	Index      int     `json:"index"`
-	Counter    int     `json:"counter"`
-	Balance    float64 `json:"balance"`
-	NextAction string  `json:"na"`
-	TotalWin   float64 `json:"total_win"`
+	Attempts    int
 }
 
 func NewPragmaticPlay(ctx context.Context, url, ua string) *PragmaticPlay {
@@ -44,13 +38,22 @@ func NewPragmaticPlay(ctx context.Context, url, ua string) *PragmaticPlay {
 	}
 }
 
+// SetDialContext overrides the client's low-level dialer, e.g. to route
+// pragmatic's session/spin requests over a WireGuard tunnel instead of the
+// default network path. Call before LoadSession.
+func (pp *PragmaticPlay) SetDialContext(dialContext func(ctx context.Context, network, addr string) (net.Conn, error)) {
+	pp.client.DialContext = dialContext
+}
+
 func (pp *PragmaticPlay) LoadSession() (*SessionData, error) {
 	// Set redirect policy on the client before making the request
 	pp.client.SetRedirectPolicy(req.NoRedirectPolicy())
 
-	resp, err := pp.client.R().
-		SetContext(pp.ctx).
-		Get(*pp.url)
+	resp, attempts, err := pp.doWithRetry(func() (*req.Response, error) {
+		return pp.client.R().
+			SetContext(pp.ctx).
+			Get(*pp.url)
+	})
 
 	if err != nil {
 		return nil, err
@@ -71,122 +74,102 @@ func (pp *PragmaticPlay) LoadSession() (*SessionData, error) {
 	return &SessionData{
 		RedirectURL: location,
 		MGCKey:      u.Query().Get("mgckey"),
+		Attempts:    attempts,
 	}, nil
 }
 
-func (pp *PragmaticPlay) DoInit(mgckey string, symbol string) (*ResponseData, error) {
-	resp, err := pp.client.R().
-		SetContext(pp.ctx).
-		SetHeaders(map[string]string{
-			"accept":       "*/*",
-			"content-type": "application/x-www-form-urlencoded",
-		}).
-		SetFormData(map[string]string{
-			"action":  "doInit",
-			"symbol":  symbol,
-			"cver":    "339188",
-			"index":   "1",
-			"counter": "1",
-			"repeat":  "0",
-			"mgckey":  mgckey,
-		}).
-		Post("/gs2c/ge/v4/gameService")
+func (pp *PragmaticPlay) DoInit(mgckey string, symbol string) (*SpinResult, error) {
+	resp, attempts, err := pp.doWithRetry(func() (*req.Response, error) {
+		return pp.client.R().
+			SetContext(pp.ctx).
+			SetHeaders(map[string]string{
+				"accept":       "*/*",
+				"content-type": "application/x-www-form-urlencoded",
+			}).
+			SetFormData(map[string]string{
+				"action":  "doInit",
+				"symbol":  symbol,
+				"cver":    "339188",
+				"index":   "1",
+				"counter": "1",
+				"repeat":  "0",
+				"mgckey":  mgckey,
+			}).
+			Post("/gs2c/ge/v4/gameService")
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return pp.ParseResponseData(resp)
+	return pp.parseResponseData(resp, attempts)
 }
 
-func (pp *PragmaticPlay) ParseResponseData(resp *req.Response) (*ResponseData, error) {
-	body := resp.String()
-
-	getInt := func(key string) int {
-		re := regexp.MustCompile(key + `=([0-9]+)`)
-		m := re.FindStringSubmatch(body)
-		if len(m) > 1 {
-			val, _ := strconv.Atoi(m[1])
-			return val
-		}
-		return 0
-	}
-
-	getFloat := func(key string) float64 {
-		re := regexp.MustCompile(key + `=([0-9.,]+)`)
-		m := re.FindStringSubmatch(body)
-		if len(m) > 1 {
-			// Remove commas before parsing
-			clean := regexp.MustCompile(`,`).ReplaceAllString(m[1], "")
-			val, _ := strconv.ParseFloat(clean, 64)
-			return val
-		}
-		return 0
-	}
+// ParseResponseData decodes a gameService response using the shared
+// key=value tokenizer, returning a *PragmaticError when the server reports
+// a failure via err= or na=e.
+func (pp *PragmaticPlay) ParseResponseData(resp *req.Response) (*SpinResult, error) {
+	return pp.parseResponseData(resp, 1)
+}
 
-	getString := func(key string) string {
-		re := regexp.MustCompile(key + `=([^&]*)`)
-		m := re.FindStringSubmatch(body)
-		if len(m) > 1 {
-			return m[1]
-		}
-		return ""
+func (pp *PragmaticPlay) parseResponseData(resp *req.Response, attempts int) (*SpinResult, error) {
+	result, err := ParseSpinResult(resp.String())
+	if err != nil {
+		return nil, err
 	}
-
-	return &ResponseData{
-		Index:      getInt("index"),
-		Counter:    getInt("counter"),
-		Balance:    getFloat("balance"),
-		TotalWin:   getFloat("tw"),
-		NextAction: getString("na"),
-	}, nil
+	result.Attempts = attempts
+	return result, nil
 }
 
-func (pp *PragmaticPlay) DoSpin(mgckey, symbol string, c, index, counter int, sInfo string) (*ResponseData, error) {
-	resp, err := pp.client.R().
-		SetContext(pp.ctx).
-		SetHeaders(map[string]string{
-			"content-type": "application/x-www-form-urlencoded",
-		}).
-		SetFormData(map[string]string{
-			"action":  "doSpin",
-			"symbol":  symbol,
-			"c":       strconv.Itoa(c),
-			"l":       "1024",
-			"sInfo":   sInfo,
-			"index":   strconv.Itoa(index),
-			"counter": strconv.Itoa(counter),
-			"repeat":  "0",
-			"mgckey":  mgckey,
-		}).
-		Post("/gs2c/ge/v4/gameService")
+func (pp *PragmaticPlay) DoSpin(mgckey, symbol string, c, index, counter int, sInfo string) (*SpinResult, error) {
+	resp, attempts, err := pp.doWithRetry(func() (*req.Response, error) {
+		return pp.client.R().
+			SetContext(pp.ctx).
+			SetHeaders(map[string]string{
+				"content-type": "application/x-www-form-urlencoded",
+			}).
+			SetFormData(map[string]string{
+				"action":  "doSpin",
+				"symbol":  symbol,
+				"c":       strconv.Itoa(c),
+				"l":       "1024",
+				"sInfo":   sInfo,
+				"index":   strconv.Itoa(index),
+				"counter": strconv.Itoa(counter),
+				"repeat":  "0",
+				"mgckey":  mgckey,
+			}).
+			Post("/gs2c/ge/v4/gameService")
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return pp.ParseResponseData(resp)
+	return pp.parseResponseData(resp, attempts)
 }
 
-func (pp *PragmaticPlay) DoCollect(mgckey, symbol string, index, counter int) (*ResponseData, error) {
-	resp, err := pp.client.R().
-		SetContext(pp.ctx).
-		SetHeaders(map[string]string{
-			"content-type": "application/x-www-form-urlencoded",
-		}).
-		SetFormData(map[string]string{
-			"symbol":  symbol,
-			"action":  "doCollect",
-			"index":   strconv.Itoa(index),
-			"counter": strconv.Itoa(counter),
-			"repeat":  "0",
-			"mgckey":  mgckey,
-		}).
-		Post("/gs2c/ge/v4/gameService")
+func (pp *PragmaticPlay) DoCollect(mgckey, symbol string, index, counter int) (*SpinResult, error) {
+	resp, attempts, err := pp.doWithRetry(func() (*req.Response, error) {
+		return pp.client.R().
+			SetContext(pp.ctx).
+			SetHeaders(map[string]string{
+				"content-type": "application/x-www-form-urlencoded",
+			}).
+			SetFormData(map[string]string{
+				"symbol":  symbol,
+				"action":  "doCollect",
+				"index":   strconv.Itoa(index),
+				"counter": strconv.Itoa(counter),
+				"repeat":  "0",
+				"mgckey":  mgckey,
+			}).
+			Post("/gs2c/ge/v4/gameService")
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return pp.ParseResponseData(resp)
+	return pp.parseResponseData(resp, attempts)
 }
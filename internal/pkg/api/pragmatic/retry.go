@@ -0,0 +1,116 @@
+package pragmatic
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/imroc/req/v3"
+)
+
+// RetryPolicy controls how PragmaticPlay retries transient failures on
+// idempotent GETs (LoadSession) and 5xx/timeout POSTs (DoInit/DoSpin/
+// DoCollect). Only DNS, TLS and network-timeout errors, plus 5xx responses,
+// are retried; everything else (malformed requests, parse failures,
+// context cancellation) is returned immediately.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	RetryTimeout   time.Duration // cumulative wall-clock budget across all attempts
+	Jitter         float64       // fraction of the backoff to randomize, e.g. 0.2
+}
+
+// DefaultRetryPolicy is a conservative policy suitable for most deployments.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	RetryTimeout:   30 * time.Second,
+	Jitter:         0.2,
+}
+
+// SetRetryPolicy enables retries on pp using policy. Without calling this,
+// PragmaticPlay behaves as before: a single attempt, no retries.
+func (pp *PragmaticPlay) SetRetryPolicy(policy RetryPolicy) {
+	pp.retryPolicy = &policy
+}
+
+// isTransientError classifies DNS resolution failures, TLS handshake
+// failures and network timeouts as retryable.
+func isTransientError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var tlsErr *tls.RecordHeaderError
+	if errors.As(err, &tlsErr) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return false
+}
+
+func backoffDuration(policy RetryPolicy, attempt int) time.Duration {
+	d := policy.InitialBackoff << (attempt - 1)
+	if d <= 0 || d > policy.MaxBackoff {
+		d = policy.MaxBackoff
+	}
+
+	if policy.Jitter <= 0 {
+		return d
+	}
+
+	delta := time.Duration(float64(d) * policy.Jitter)
+	if delta <= 0 {
+		return d
+	}
+
+	return d - delta/2 + time.Duration(rand.Int63n(int64(delta)))
+}
+
+// doWithRetry runs fn under pp's retry policy, retrying transient failures
+// with exponential backoff and jitter until a non-transient outcome is
+// reached, MaxAttempts is exhausted, or the cumulative elapsed time exceeds
+// RetryTimeout. It returns the final response (if any), the number of
+// attempts made and the final error.
+func (pp *PragmaticPlay) doWithRetry(fn func() (*req.Response, error)) (*req.Response, int, error) {
+	if pp.retryPolicy == nil {
+		resp, err := fn()
+		return resp, 1, err
+	}
+
+	policy := *pp.retryPolicy
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		resp, err := fn()
+
+		transient := false
+		if err != nil {
+			transient = isTransientError(err)
+		} else if resp.StatusCode >= 500 {
+			transient = true
+			err = fmt.Errorf("pragmatic: server error, status %d", resp.StatusCode)
+		}
+
+		if !transient || attempt >= policy.MaxAttempts || time.Since(start) > policy.RetryTimeout {
+			return resp, attempt, err
+		}
+
+		select {
+		case <-pp.ctx.Done():
+			return resp, attempt, pp.ctx.Err()
+		case <-time.After(backoffDuration(policy, attempt)):
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package yarun
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dayusch/internal/pkg/vectors"
+)
+
+const vectorsDir = "testdata/vectors"
+
+var update = flag.Bool("update", false, "re-record vectors against a live endpoint named by YARUN_TEST_URL/YARUN_TEST_TOKEN")
+
+// dispatch calls the YarunApi method vector.Name names and returns
+// whatever that method decoded, so TestConformance and -update can share
+// one call site per vector.
+func dispatch(t *testing.T, api *YarunApi, v vectors.Vector) any {
+	t.Helper()
+	ctx := context.Background()
+
+	switch v.Name {
+	case "get_proxies":
+		resp, err := api.GetProxies(ctx, 2)
+		if err != nil {
+			t.Fatalf("GetProxies: %v", err)
+		}
+		return resp
+	case "get_blocked_proxies":
+		resp, err := api.GetBlockedProxies(ctx, 2)
+		if err != nil {
+			t.Fatalf("GetBlockedProxies: %v", err)
+		}
+		return resp
+	case "block_proxy":
+		resp, err := api.BlockProxy(ctx, "proxy-1")
+		if err != nil {
+			t.Fatalf("BlockProxy: %v", err)
+		}
+		return resp
+	case "unblock_proxy":
+		resp, err := api.UnblockProxy(ctx, "proxy-1", "1.2.3.4", false)
+		if err != nil {
+			t.Fatalf("UnblockProxy: %v", err)
+		}
+		return resp
+	case "update_user_balance":
+		resp, err := api.UpdateUserBalance(ctx, "user-1", 100.5, 5)
+		if err != nil {
+			t.Fatalf("UpdateUserBalance: %v", err)
+		}
+		return resp
+	default:
+		t.Fatalf("no dispatch registered for vector %q", v.Name)
+		return nil
+	}
+}
+
+// TestConformance replays every vector in testdata/vectors against an
+// httptest.Server and diffs the decoded result against its golden Expect.
+func TestConformance(t *testing.T) {
+	vecs, err := vectors.Load(vectorsDir)
+	if err != nil {
+		t.Fatalf("load vectors: %v", err)
+	}
+	if len(vecs) == 0 {
+		t.Fatalf("no vectors found in %s", vectorsDir)
+	}
+
+	for _, v := range vecs {
+		v := v
+		t.Run(v.Name, func(t *testing.T) {
+			if *update {
+				recordVector(t, v)
+				return
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != v.Path || r.Method != v.Method {
+					t.Errorf("got %s %s, want %s %s", r.Method, r.URL.Path, v.Method, v.Path)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(v.ResponseStatus)
+				w.Write(v.ResponseBody)
+			}))
+			defer server.Close()
+
+			api := NewYarunApi(server.URL, "test-token")
+			got := dispatch(t, api, v)
+			assertMatchesExpect(t, got, v.Expect)
+		})
+	}
+}
+
+// assertMatchesExpect round-trips got through JSON and compares the
+// canonicalized result against want, so field order and whitespace don't
+// spuriously fail the comparison.
+func assertMatchesExpect(t *testing.T, got any, want json.RawMessage) {
+	t.Helper()
+
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshal result: %v", err)
+	}
+
+	var gotNormalized, wantNormalized any
+	if err := json.Unmarshal(gotJSON, &gotNormalized); err != nil {
+		t.Fatalf("normalize got: %v", err)
+	}
+	if err := json.Unmarshal(want, &wantNormalized); err != nil {
+		t.Fatalf("normalize want: %v", err)
+	}
+
+	gotCanon, _ := json.Marshal(gotNormalized)
+	wantCanon, _ := json.Marshal(wantNormalized)
+	if string(gotCanon) != string(wantCanon) {
+		t.Errorf("decoded result mismatch:\n got:  %s\n want: %s", gotCanon, wantCanon)
+	}
+}
+
+// recordVector re-runs vector v against a live endpoint and rewrites its
+// Expect field in place, for a -update run.
+func recordVector(t *testing.T, v vectors.Vector) {
+	t.Helper()
+
+	baseURL := os.Getenv("YARUN_TEST_URL")
+	token := os.Getenv("YARUN_TEST_TOKEN")
+	if baseURL == "" || token == "" {
+		t.Skip("set YARUN_TEST_URL and YARUN_TEST_TOKEN to re-record against a live endpoint")
+	}
+
+	api := NewYarunApi(baseURL, token)
+	got := dispatch(t, api, v)
+
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshal result: %v", err)
+	}
+	v.Expect = gotJSON
+
+	if err := vectors.Save(filepath.Join(vectorsDir, v.Name+".json"), v); err != nil {
+		t.Fatalf("save vector: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package yarun
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// ErrUpstreamDown is returned by every YarunApi method once the
+// background heartbeat has observed heartbeatFailureThreshold
+// consecutive /health failures, so a caller fails fast instead of
+// blocking out to its own request timeout against an upstream that's
+// already known to be dead.
+var ErrUpstreamDown = errors.New("yarun: upstream unreachable")
+
+// heartbeatFailureThreshold is how many consecutive failed heartbeats
+// before YarunApi starts short-circuiting calls with ErrUpstreamDown.
+const heartbeatFailureThreshold = 3
+
+// heartbeatMinBackoff/heartbeatMaxBackoff bound the reconnect-state
+// polling interval once the heartbeat starts failing: 1s on the first
+// failure, doubling up to a 60s ceiling.
+const (
+	heartbeatMinBackoff = 1 * time.Second
+	heartbeatMaxBackoff = 60 * time.Second
+	heartbeatJitter     = 0.3
+)
+
+// Option configures a YarunApi at construction time.
+type Option func(*YarunApi)
+
+// WithHeartbeatInterval overrides how often YarunApi pings /health while
+// healthy. The default is 10 seconds. It has no effect once the
+// heartbeat is in its reconnect state, which paces itself on its own
+// exponential backoff instead.
+func WithHeartbeatInterval(d time.Duration) Option {
+	return func(y *YarunApi) { y.heartbeatInterval = d }
+}
+
+// heartbeatBackoff returns the delay before the next /health probe
+// given consecutiveFailures (>=1): heartbeatMinBackoff doubled per
+// failure up to heartbeatMaxBackoff, jittered across a window
+// heartbeatJitter of the delay wide (+/- half of that) so replicas that started failing together don't all retry in lockstep.
+func heartbeatBackoff(consecutiveFailures int) time.Duration {
+	d := heartbeatMinBackoff << (consecutiveFailures - 1)
+	if d <= 0 || d > heartbeatMaxBackoff {
+		d = heartbeatMaxBackoff
+	}
+
+	delta := time.Duration(float64(d) * heartbeatJitter)
+	if delta <= 0 {
+		return d
+	}
+	return d - delta/2 + time.Duration(rand.Int63n(int64(delta)))
+}
+
+// runHeartbeat pings /health on heartbeatInterval while healthy, or on
+// heartbeatBackoff(consecutiveFailures) once it isn't, until
+// heartbeatStop is closed. It's started once by NewYarunApi and stopped by Stop.
+func (y *YarunApi) runHeartbeat() {
+	defer close(y.heartbeatDone)
+
+	for {
+		failures := y.consecutiveFailures()
+		interval := y.heartbeatInterval
+		if failures > 0 {
+			interval = heartbeatBackoff(failures)
+		}
+
+		select {
+		case <-y.heartbeatStop:
+			return
+		case <-time.After(interval):
+		}
+
+		y.checkHealth()
+	}
+}
+
+// checkHealth runs one /health probe and updates the consecutive
+// failure count it drives isUp/ErrUpstreamDown from.
+func (y *YarunApi) checkHealth() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := y.healthClient.R().SetContext(ctx).Get("/health")
+	healthy := err == nil && resp.IsSuccessState()
+
+	y.healthMu.Lock()
+	defer y.healthMu.Unlock()
+
+	if healthy {
+		if y.failures >= heartbeatFailureThreshold {
+			y.logger.Info(ctx, "yarun upstream recovered")
+		}
+		y.failures = 0
+		return
+	}
+
+	y.failures++
+	if y.failures == heartbeatFailureThreshold {
+		y.logger.Warn(ctx, "yarun upstream down, short-circuiting calls with ErrUpstreamDown", "consecutive_failures", y.failures)
+	} else {
+		y.logger.Warn(ctx, "yarun heartbeat failed", "consecutive_failures", y.failures, "error", err)
+	}
+}
+
+func (y *YarunApi) consecutiveFailures() int {
+	y.healthMu.RLock()
+	defer y.healthMu.RUnlock()
+	return y.failures
+}
+
+// isUp reports whether the last heartbeatFailureThreshold consecutive
+// /health checks have not all failed.
+func (y *YarunApi) isUp() bool {
+	return y.consecutiveFailures() < heartbeatFailureThreshold
+}
@@ -0,0 +1,348 @@
+package yarun
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	proxyHealthEWMAAlpha          = 0.3
+	defaultMaxConsecutiveFailures = 3
+	defaultLatencyBlockThreshold  = 5 * time.Second
+	defaultLocalCooldown          = time.Minute
+)
+
+// ProxySelector wraps GetProxies/BlockProxy/UnblockProxy behind one
+// Acquire call, so callers stop picking a proxy and deciding whether
+// to block it themselves: Acquire hands back a proxy plus a Release
+// callback that feeds the outcome into the proxy's tracked health and
+// blocks/unblocks it upstream as needed.
+type ProxySelector interface {
+	// Acquire returns a proxy to use and a Release func the caller
+	// must invoke exactly once with whether the request succeeded and
+	// how long it took.
+	Acquire(ctx context.Context) (proxy *ProxyResponse, release func(success bool, latency time.Duration), err error)
+
+	// SetClient repoints the selector's pool at a freshly-constructed
+	// client, e.g. after a credential reload, without losing the
+	// cached proxies or their tracked health.
+	SetClient(api *YarunApi)
+}
+
+// proxyHealth is one proxy's tracked health: an EWMA of its success
+// rate and latency, its current run of consecutive failures, and
+// whether it's in a locally-tracked cooldown (set after Release blocks
+// it, cleared once it's unblocked).
+type proxyHealth struct {
+	mu                  sync.Mutex
+	successRate         float64 // EWMA, in [0,1]
+	avgLatency          time.Duration
+	samples             int
+	consecutiveFailures int
+	cooldownUntil       time.Time
+}
+
+func (h *proxyHealth) record(success bool, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var observed float64
+	if success {
+		observed = 1
+		h.consecutiveFailures = 0
+	} else {
+		h.consecutiveFailures++
+	}
+
+	if h.samples == 0 {
+		h.successRate = observed
+		h.avgLatency = latency
+	} else {
+		h.successRate = proxyHealthEWMAAlpha*observed + (1-proxyHealthEWMAAlpha)*h.successRate
+		h.avgLatency = time.Duration(proxyHealthEWMAAlpha*float64(latency) + (1-proxyHealthEWMAAlpha)*float64(h.avgLatency))
+	}
+	h.samples++
+}
+
+func (h *proxyHealth) snapshot() (successRate float64, avgLatency time.Duration, consecutiveFailures int, cooldownUntil time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.successRate, h.avgLatency, h.consecutiveFailures, h.cooldownUntil
+}
+
+func (h *proxyHealth) setCooldown(until time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cooldownUntil = until
+}
+
+// scoreOf ranks a proxy for the health-aware selectors: a higher
+// success rate is better, a higher latency is worse.
+func scoreOf(successRate float64, avgLatency time.Duration) float64 {
+	return successRate - avgLatency.Seconds()/10
+}
+
+// proxyPool is the cached set of proxies shared by every selector
+// implementation below, refilled from GetProxies once it's drained.
+// Acquire pops a proxy out of it; Release pushes it back in, so two
+// concurrent Acquire calls never hand out the same proxy.
+type proxyPool struct {
+	api   *YarunApi
+	limit int
+
+	mu      sync.Mutex
+	proxies []ProxyResponse
+	health  map[string]*proxyHealth
+}
+
+func newProxyPool(api *YarunApi, limit int) *proxyPool {
+	return &proxyPool{api: api, limit: limit, health: make(map[string]*proxyHealth)}
+}
+
+func (p *proxyPool) refresh(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.proxies) > 0 {
+		return nil
+	}
+
+	resp, err := p.api.GetProxies(ctx, p.limit)
+	if err != nil {
+		return err
+	}
+
+	p.proxies = append(p.proxies, resp.Proxies...)
+	for _, proxy := range p.proxies {
+		if _, ok := p.health[proxy.ID]; !ok {
+			p.health[proxy.ID] = &proxyHealth{}
+		}
+	}
+	return nil
+}
+
+func (p *proxyPool) snapshot() []ProxyResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]ProxyResponse, len(p.proxies))
+	copy(out, p.proxies)
+	return out
+}
+
+func (p *proxyPool) take(proxy ProxyResponse) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, pr := range p.proxies {
+		if pr.ID == proxy.ID {
+			p.proxies = append(p.proxies[:i], p.proxies[i+1:]...)
+			return
+		}
+	}
+}
+
+func (p *proxyPool) put(proxy ProxyResponse) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.proxies = append(p.proxies, proxy)
+}
+
+// setClient repoints the pool at api, leaving its cached proxies and
+// health tracking untouched.
+func (p *proxyPool) setClient(api *YarunApi) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.api = api
+}
+
+// getAPI returns the pool's current client, guarding against a
+// concurrent setClient swapping p.api out from under a Release call.
+func (p *proxyPool) getAPI() *YarunApi {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.api
+}
+
+func (p *proxyPool) healthFor(proxyID string) *proxyHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h, ok := p.health[proxyID]
+	if !ok {
+		h = &proxyHealth{}
+		p.health[proxyID] = h
+	}
+	return h
+}
+
+// releaseFunc builds the Release callback every selector below hands
+// back from Acquire: fold the outcome into proxy's health, block it
+// upstream on a failed request once it has reached maxConsecutiveFailures
+// or its EWMA latency has reached latencyThreshold (a successful request
+// never blocks it, however slow), unblock it once it recovers from an earlier local cooldown, then
+// return it to the pool either way.
+func (p *proxyPool) releaseFunc(ctx context.Context, proxy ProxyResponse, maxConsecutiveFailures int, latencyThreshold time.Duration) func(success bool, latency time.Duration) {
+	return func(success bool, latency time.Duration) {
+		h := p.healthFor(proxy.ID)
+		_, _, _, cooldownUntil := h.snapshot()
+		wasCoolingDown := time.Now().Before(cooldownUntil)
+
+		h.record(success, latency)
+		_, avgLatency, consecutiveFailures, _ := h.snapshot()
+
+		switch {
+		case success && wasCoolingDown:
+			h.setCooldown(time.Time{})
+			p.getAPI().UnblockProxy(ctx, proxy.ID, proxy.IP, false)
+		case !success && (consecutiveFailures >= maxConsecutiveFailures || avgLatency >= latencyThreshold):
+			h.setCooldown(time.Now().Add(defaultLocalCooldown))
+			p.getAPI().BlockProxy(ctx, proxy.ID)
+		}
+
+		p.put(proxy)
+	}
+}
+
+// roundRobinSelector hands out proxies from the cached pool in the
+// order GetProxies returned them, cycling back through as they're
+// released.
+type roundRobinSelector struct {
+	pool                   *proxyPool
+	maxConsecutiveFailures int
+	latencyThreshold       time.Duration
+}
+
+// NewRoundRobinSelector returns a ProxySelector that cycles through up
+// to limit cached proxies in order.
+func NewRoundRobinSelector(api *YarunApi, limit int) ProxySelector {
+	return &roundRobinSelector{
+		pool:                   newProxyPool(api, limit),
+		maxConsecutiveFailures: defaultMaxConsecutiveFailures,
+		latencyThreshold:       defaultLatencyBlockThreshold,
+	}
+}
+
+func (s *roundRobinSelector) Acquire(ctx context.Context) (*ProxyResponse, func(bool, time.Duration), error) {
+	if err := s.pool.refresh(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	proxies := s.pool.snapshot()
+	if len(proxies) == 0 {
+		return nil, nil, fmt.Errorf("no proxies available")
+	}
+
+	proxy := proxies[0]
+	s.pool.take(proxy)
+	return &proxy, s.pool.releaseFunc(ctx, proxy, s.maxConsecutiveFailures, s.latencyThreshold), nil
+}
+
+func (s *roundRobinSelector) SetClient(api *YarunApi) {
+	s.pool.setClient(api)
+}
+
+// ewmaLatencySelector picks the best-scoring proxy not currently in a
+// local cooldown, by EWMA success rate weighted against EWMA latency.
+// When every cached proxy is cooling down, it hands out the first one.
+type ewmaLatencySelector struct {
+	pool                   *proxyPool
+	maxConsecutiveFailures int
+	latencyThreshold       time.Duration
+}
+
+// NewEWMALatencySelector returns a ProxySelector that always hands out
+// whichever cached proxy currently scores best on success rate and
+// latency.
+func NewEWMALatencySelector(api *YarunApi, limit int) ProxySelector {
+	return &ewmaLatencySelector{
+		pool:                   newProxyPool(api, limit),
+		maxConsecutiveFailures: defaultMaxConsecutiveFailures,
+		latencyThreshold:       defaultLatencyBlockThreshold,
+	}
+}
+
+func (s *ewmaLatencySelector) Acquire(ctx context.Context) (*ProxyResponse, func(bool, time.Duration), error) {
+	if err := s.pool.refresh(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	proxies := s.pool.snapshot()
+	if len(proxies) == 0 {
+		return nil, nil, fmt.Errorf("no proxies available")
+	}
+
+	now := time.Now()
+	best := proxies[0]
+	var bestScore float64
+	haveCandidate := false
+
+	for _, proxy := range proxies {
+		successRate, avgLatency, _, cooldownUntil := s.pool.healthFor(proxy.ID).snapshot()
+		if now.Before(cooldownUntil) {
+			continue
+		}
+		if score := scoreOf(successRate, avgLatency); !haveCandidate || score > bestScore {
+			best, bestScore, haveCandidate = proxy, score, true
+		}
+	}
+
+	s.pool.take(best)
+	return &best, s.pool.releaseFunc(ctx, best, s.maxConsecutiveFailures, s.latencyThreshold), nil
+}
+
+func (s *ewmaLatencySelector) SetClient(api *YarunApi) {
+	s.pool.setClient(api)
+}
+
+// powerOfTwoSelector samples two random cached proxies and takes the
+// healthier of the two, trading the EWMA selector's "always pick the
+// single best" behavior (which piles every request onto one proxy) for
+// spread across the pool.
+type powerOfTwoSelector struct {
+	pool                   *proxyPool
+	maxConsecutiveFailures int
+	latencyThreshold       time.Duration
+}
+
+// NewPowerOfTwoSelector returns a ProxySelector that samples two
+// random cached proxies per Acquire and hands out the healthier one.
+func NewPowerOfTwoSelector(api *YarunApi, limit int) ProxySelector {
+	return &powerOfTwoSelector{
+		pool:                   newProxyPool(api, limit),
+		maxConsecutiveFailures: defaultMaxConsecutiveFailures,
+		latencyThreshold:       defaultLatencyBlockThreshold,
+	}
+}
+
+func (s *powerOfTwoSelector) Acquire(ctx context.Context) (*ProxyResponse, func(bool, time.Duration), error) {
+	if err := s.pool.refresh(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	proxies := s.pool.snapshot()
+	if len(proxies) == 0 {
+		return nil, nil, fmt.Errorf("no proxies available")
+	}
+
+	chosen := proxies[rand.Intn(len(proxies))]
+	if len(proxies) > 1 {
+		other := proxies[rand.Intn(len(proxies))]
+		for other.ID == chosen.ID {
+			other = proxies[rand.Intn(len(proxies))]
+		}
+
+		chosenSuccess, chosenLatency, _, _ := s.pool.healthFor(chosen.ID).snapshot()
+		otherSuccess, otherLatency, _, _ := s.pool.healthFor(other.ID).snapshot()
+		if scoreOf(otherSuccess, otherLatency) > scoreOf(chosenSuccess, chosenLatency) {
+			chosen = other
+		}
+	}
+
+	s.pool.take(chosen)
+	return &chosen, s.pool.releaseFunc(ctx, chosen, s.maxConsecutiveFailures, s.latencyThreshold), nil
+}
+
+func (s *powerOfTwoSelector) SetClient(api *YarunApi) {
+	s.pool.setClient(api)
+}
@@ -3,14 +3,40 @@ package yarun
 import (
 	"context"
 	"strconv"
+	"sync"
 	"time"
 
+	"dayusch/internal/pkg/apiclient"
+	"dayusch/internal/pkg/auth"
+	"dayusch/internal/pkg/obs"
+
 	"github.com/imroc/req/v3"
 )
 
+// defaultHeartbeatInterval is how often a YarunApi pings /health while
+// healthy, unless overridden by WithHeartbeatInterval.
+const defaultHeartbeatInterval = 10 * time.Second
+
 // YarunApi represents the yarun API client
 type YarunApi struct {
 	client *req.Client
+	logger *obs.Logger
+	auth   auth.Auth
+
+	// healthClient probes /health on its own, without apiclient.Attach:
+	// the heartbeat wants a plain, short-timeout check every interval,
+	// not client's retry/backoff behavior (meant for real request
+	// traffic) or its breaker's "open" state feeding back into the very
+	// probe that's supposed to detect recovery independently.
+	healthClient *req.Client
+
+	heartbeatInterval time.Duration
+	heartbeatStop     chan struct{}
+	heartbeatDone     chan struct{}
+	stopOnce          sync.Once
+
+	healthMu sync.RWMutex
+	failures int
 }
 
 // ProxyResponse represents a proxy object
@@ -69,24 +95,96 @@ type UpdateBalanceResponse struct {
 	Ok bool `json:"ok"`
 }
 
-// NewYarunApi creates a new yarun API client
-func NewYarunApi(baseURL, token string) *YarunApi {
+// NewYarunApi creates a new yarun API client. tokenDescriptor is an
+// auth.New descriptor ("htpasswd://file=...", "env://VAR", ...); a bare
+// token string still works via auth's static fallback, so existing
+// callers don't need to change.
+//
+// It also starts a background heartbeat against /health: once it has
+// failed heartbeatFailureThreshold times in a row, every method below
+// returns ErrUpstreamDown immediately instead of blocking out to its own
+// request timeout, until a probe succeeds again.
+func NewYarunApi(baseURL, tokenDescriptor string, opts ...Option) *YarunApi {
+	a, err := auth.New(tokenDescriptor)
+	if err != nil {
+		// NewYarunApi has no error return, so a malformed descriptor
+		// falls back to a client that fails every request's Header()
+		// call with that same error, instead of panicking at startup.
+		a = auth.Failing(err)
+	}
+
 	client := req.C().
 		SetBaseURL(baseURL).
-		SetCommonHeader("x-token", token).
 		SetCommonHeader("Content-Type", "application/json").
 		SetTimeout(30 * time.Second)
 
+	client.OnBeforeRequest(func(c *req.Client, r *req.Request) error {
+		header, err := a.Header(r.Context())
+		if err != nil {
+			return err
+		}
+		r.SetHeader("x-token", header)
+		return nil
+	})
+
 	// client.DevMode()
 
-	return &YarunApi{
-		client: client,
+	logger := obs.Default()
+	obs.AttachHTTPDebugLogging(client, logger)
+	apiclient.Attach(client, apiclient.DefaultConfig())
+
+	healthClient := req.C().
+		SetBaseURL(baseURL).
+		SetTimeout(5 * time.Second)
+
+	healthClient.OnBeforeRequest(func(c *req.Client, r *req.Request) error {
+		header, err := a.Header(r.Context())
+		if err != nil {
+			return err
+		}
+		r.SetHeader("x-token", header)
+		return nil
+	})
+
+	y := &YarunApi{
+		client:            client,
+		healthClient:      healthClient,
+		logger:            logger,
+		auth:              a,
+		heartbeatInterval: defaultHeartbeatInterval,
+		heartbeatStop:     make(chan struct{}),
+		heartbeatDone:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(y)
 	}
+
+	go y.runHeartbeat()
+
+	return y
+}
+
+// Stop releases the client's auth backend (e.g. a file watcher) and
+// stops the background heartbeat, waiting for it to exit. Safe to call
+// more than once (e.g. two concurrent ReloadConfig calls both observing
+// the same stale client): only the first call does anything.
+func (y *YarunApi) Stop() {
+	y.stopOnce.Do(func() {
+		y.auth.Stop()
+		close(y.heartbeatStop)
+		<-y.heartbeatDone
+	})
 }
 
 // GetProxies gets n proxies from the API with round robin functionality
 func (y *YarunApi) GetProxies(ctx context.Context, limit int) (*GetProxiesResponse, error) {
+	if !y.isUp() {
+		return nil, ErrUpstreamDown
+	}
+
 	var response GetProxiesResponse
+	start := time.Now()
 
 	resp, err := y.client.R().
 		SetContext(ctx).
@@ -95,23 +193,31 @@ func (y *YarunApi) GetProxies(ctx context.Context, limit int) (*GetProxiesRespon
 		Get("/proxy")
 
 	if err != nil {
+		y.logger.APICall(ctx, "GET /proxy", time.Since(start), "error")
 		return nil, err
 	}
 
 	if !resp.IsSuccessState() {
+		y.logger.APICall(ctx, "GET /proxy", time.Since(start), resp.Status)
 		return nil, resp.Err
 	}
 
+	y.logger.APICall(ctx, "GET /proxy", time.Since(start), resp.Status, "proxy_count", len(response.Proxies))
 	return &response, nil
 }
 
 // BlockProxy sets a 1-hour cooldown on a specific proxy
 func (y *YarunApi) BlockProxy(ctx context.Context, proxyID string) (*BlockProxyResponse, error) {
+	if !y.isUp() {
+		return nil, ErrUpstreamDown
+	}
+
 	request := BlockProxyRequest{
 		ID: proxyID,
 	}
 
 	var response BlockProxyResponse
+	start := time.Now()
 
 	resp, err := y.client.R().
 		SetContext(ctx).
@@ -120,19 +226,27 @@ func (y *YarunApi) BlockProxy(ctx context.Context, proxyID string) (*BlockProxyR
 		Post("/proxy/blocked")
 
 	if err != nil {
+		y.logger.APICall(ctx, "POST /proxy/blocked", time.Since(start), "error", "proxy_id", proxyID)
 		return nil, err
 	}
 
 	if !resp.IsSuccessState() {
+		y.logger.APICall(ctx, "POST /proxy/blocked", time.Since(start), resp.Status, "proxy_id", proxyID)
 		return nil, resp.Err
 	}
 
+	y.logger.APICall(ctx, "POST /proxy/blocked", time.Since(start), resp.Status, "proxy_id", proxyID)
 	return &response, nil
 }
 
 // GetBlockedProxies gets blocked proxies from the API
 func (y *YarunApi) GetBlockedProxies(ctx context.Context, limit int) (*GetBlockedProxiesResponse, error) {
+	if !y.isUp() {
+		return nil, ErrUpstreamDown
+	}
+
 	var response GetBlockedProxiesResponse
+	start := time.Now()
 
 	resp, err := y.client.R().
 		SetContext(ctx).
@@ -141,18 +255,25 @@ func (y *YarunApi) GetBlockedProxies(ctx context.Context, limit int) (*GetBlocke
 		Get("/proxy/blocked")
 
 	if err != nil {
+		y.logger.APICall(ctx, "GET /proxy/blocked", time.Since(start), "error")
 		return nil, err
 	}
 
 	if !resp.IsSuccessState() {
+		y.logger.APICall(ctx, "GET /proxy/blocked", time.Since(start), resp.Status)
 		return nil, resp.Err
 	}
 
+	y.logger.APICall(ctx, "GET /proxy/blocked", time.Since(start), resp.Status, "proxy_count", len(response.Proxies))
 	return &response, nil
 }
 
 // UnblockProxy unblocks a proxy and updates its IP
 func (y *YarunApi) UnblockProxy(ctx context.Context, proxyID, newIP string, isBlocked bool) (*UnblockProxyResponse, error) {
+	if !y.isUp() {
+		return nil, ErrUpstreamDown
+	}
+
 	request := UnblockProxyRequest{
 		ID:        proxyID,
 		IP:        newIP,
@@ -160,6 +281,7 @@ func (y *YarunApi) UnblockProxy(ctx context.Context, proxyID, newIP string, isBl
 	}
 
 	var response UnblockProxyResponse
+	start := time.Now()
 
 	resp, err := y.client.R().
 		SetContext(ctx).
@@ -168,17 +290,24 @@ func (y *YarunApi) UnblockProxy(ctx context.Context, proxyID, newIP string, isBl
 		Post("/proxy/unblock")
 
 	if err != nil {
+		y.logger.APICall(ctx, "POST /proxy/unblock", time.Since(start), "error", "proxy_id", proxyID, "proxy_ip", newIP)
 		return nil, err
 	}
 
 	if !resp.IsSuccessState() {
+		y.logger.APICall(ctx, "POST /proxy/unblock", time.Since(start), resp.Status, "proxy_id", proxyID, "proxy_ip", newIP)
 		return nil, resp.Err
 	}
 
+	y.logger.APICall(ctx, "POST /proxy/unblock", time.Since(start), resp.Status, "proxy_id", proxyID, "proxy_ip", newIP)
 	return &response, nil
 }
 
 func (y *YarunApi) UpdateUserBalance(ctx context.Context, userID string, balance, coin float64) (*UpdateBalanceResponse, error) {
+	if !y.isUp() {
+		return nil, ErrUpstreamDown
+	}
+
 	request := UpdateBalanceRequest{
 		ID:      userID,
 		Balance: balance,
@@ -186,6 +315,7 @@ func (y *YarunApi) UpdateUserBalance(ctx context.Context, userID string, balance
 	}
 
 	var response UpdateBalanceResponse
+	start := time.Now()
 
 	resp, err := y.client.R().
 		SetContext(ctx).
@@ -194,12 +324,15 @@ func (y *YarunApi) UpdateUserBalance(ctx context.Context, userID string, balance
 		Post("/user/balance")
 
 	if err != nil {
+		y.logger.APICall(ctx, "POST /user/balance", time.Since(start), "error", "user_id", userID)
 		return nil, err
 	}
 
 	if !resp.IsSuccessState() {
+		y.logger.APICall(ctx, "POST /user/balance", time.Since(start), resp.Status, "user_id", userID)
 		return nil, resp.Err
 	}
 
+	y.logger.APICall(ctx, "POST /user/balance", time.Since(start), resp.Status, "user_id", userID)
 	return &response, nil
 }
@@ -0,0 +1,159 @@
+package apiclient
+
+import (
+	"sync"
+	"time"
+)
+
+// breakerState mirrors the classic three-state circuit breaker: closed
+// passes every request, open fails fast, half-open lets one probe through
+// to decide whether to close again or reopen.
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateHalfOpen
+	stateOpen
+)
+
+// breaker trips open after threshold consecutive failures inside window,
+// and resets itself to half-open once cooldown has elapsed.
+type breaker struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+
+	lock             sync.Mutex
+	st               breakerState
+	consecutiveFails int
+	windowStart      time.Time
+	openedAt         time.Time
+	probeInFlight    bool
+}
+
+func newBreaker(cfg Config) *breaker {
+	return &breaker{
+		threshold: cfg.BreakerThreshold,
+		window:    cfg.BreakerWindow,
+		cooldown:  cfg.BreakerCooldown,
+		st:        stateClosed,
+	}
+}
+
+// allow reports whether a request may proceed. An open breaker flips to
+// half-open once cooldown has elapsed, but only the first caller to observe
+// that gets let through as the probe; every other caller keeps failing fast
+// until the probe's outcome closes or reopens the breaker.
+func (b *breaker) allow() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.st == stateOpen && time.Since(b.openedAt) >= b.cooldown {
+		b.st = stateHalfOpen
+		b.probeInFlight = false
+	}
+
+	switch b.st {
+	case stateOpen:
+		return false
+	case stateHalfOpen:
+		if b.probeInFlight {
+			return false
+		}
+		b.probeInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+// recordSuccess closes the breaker, clearing any failure streak. A
+// successful half-open probe is what lets it close again.
+func (b *breaker) recordSuccess() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.consecutiveFails = 0
+	b.probeInFlight = false
+	b.st = stateClosed
+}
+
+// recordFailure counts a failure toward threshold within window, tripping
+// the breaker open once threshold is reached. A failed half-open probe
+// reopens it immediately.
+func (b *breaker) recordFailure() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.st == stateHalfOpen {
+		b.probeInFlight = false
+		b.trip()
+		return
+	}
+
+	now := time.Now()
+	if b.windowStart.IsZero() || now.Sub(b.windowStart) > b.window {
+		b.windowStart = now
+		b.consecutiveFails = 0
+	}
+	b.consecutiveFails++
+
+	if b.consecutiveFails >= b.threshold {
+		b.trip()
+	}
+}
+
+// trip must be called with lock held.
+func (b *breaker) trip() {
+	b.st = stateOpen
+	b.openedAt = time.Now()
+}
+
+func (b *breaker) state() breakerState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.st
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*breaker{}
+)
+
+// breakerKey scopes a breaker to a specific host in addition to
+// method+path, so two clients that happen to share a method+path (e.g.
+// YarunApi's "GET /proxy" before and after batchproxyplay's ReloadConfig
+// points it at a different yarun_base_url) don't share breaker state:
+// a failure streak against one host would otherwise fail-fast-block
+// requests to the other, unrelated host for the rest of its cooldown.
+func breakerKey(host, method, path string) string {
+	return host + " " + method + " " + path
+}
+
+// breakerFor returns the shared breaker for key (see breakerKey),
+// creating it with cfg on first use. cfg is ignored on subsequent calls
+// for the same key.
+func breakerFor(key string, cfg Config) *breaker {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	b, ok := registry[key]
+	if !ok {
+		b = newBreaker(cfg)
+		registry[key] = b
+	}
+	return b
+}
+
+// CircuitOpen reports whether the breaker for host+method+path is
+// currently open, so a caller like BatchApp.Run can decide to sleep
+// longer instead of hammering a known-down dependency.
+func CircuitOpen(host, method, path string) bool {
+	registryMu.Lock()
+	b, ok := registry[breakerKey(host, method, path)]
+	registryMu.Unlock()
+	if !ok {
+		return false
+	}
+	return b.state() == stateOpen
+}
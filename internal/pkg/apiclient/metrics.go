@@ -0,0 +1,23 @@
+package apiclient
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// requestsTotal and circuitState are process-wide: apiclient.Attach is
+// called once per API client (YarunApi, CFBatchApi, ...) but all of them
+// share one operator-facing view of upstream health, so they register on
+// the default registerer rather than a private one like batchproxyplay's
+// per-run metrics.
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_requests_total",
+		Help: "Requests made through apiclient, labelled by endpoint and result.",
+	}, []string{"endpoint", "result"})
+
+	circuitState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "api_circuit_state",
+		Help: "Circuit breaker state per endpoint: 0=closed 1=half_open 2=open.",
+	}, []string{"endpoint"})
+)
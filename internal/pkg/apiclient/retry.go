@@ -0,0 +1,64 @@
+package apiclient
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/imroc/req/v3"
+)
+
+// decorrelatedJitter implements the AWS "decorrelated jitter" backoff:
+// sleep = min(cap, random_between(base, prev*3)), unrolled from attempt 1
+// since req's retry interval callback only hands us the attempt number,
+// not the previous sleep.
+func decorrelatedJitter(base, cap time.Duration, attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	sleep := base
+	for i := 0; i < attempt; i++ {
+		upper := sleep * 3
+		if upper > cap {
+			upper = cap
+		}
+		if upper <= base {
+			sleep = base
+			continue
+		}
+		sleep = base + time.Duration(rand.Int63n(int64(upper-base)))
+	}
+
+	if sleep > cap {
+		sleep = cap
+	}
+	return sleep
+}
+
+// retryAfter reads a 429 response's Retry-After header, supporting both
+// the delay-seconds and HTTP-date forms, so the server's own guidance
+// always wins over our backoff schedule.
+func retryAfter(resp *req.Response) (time.Duration, bool) {
+	if resp == nil || resp.Response == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if when, err := http.ParseTime(value); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d, true
+		}
+	}
+
+	return 0, false
+}
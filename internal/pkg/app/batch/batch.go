@@ -4,21 +4,27 @@ import (
 	"context"
 	"dayusch/internal/pkg/api/cfbatch"
 	"dayusch/internal/pkg/db"
+	"dayusch/internal/pkg/obs"
 	"dayusch/internal/pkg/repo"
 	"os"
 	"sync"
+	"time"
 
-	"github.com/charmbracelet/log"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// cursorJobClaim identifies this worker's checkpoint in repo.CursorRepo.
+const cursorJobClaim = "claim"
+
 type BatchApp struct {
-	ctx context.Context
+	ctx    context.Context
+	logger *obs.Logger
 }
 
 func NewBatchApp(ctx context.Context) *BatchApp {
 	return &BatchApp{
-		ctx: ctx,
+		ctx:    ctx,
+		logger: obs.Default(),
 	}
 }
 
@@ -32,55 +38,92 @@ func (a *BatchApp) Run() {
 
 	md, err := db.NewDbCon(a.ctx, uri, dbName)
 	if err != nil {
-		log.Fatal(err)
+		a.logger.Fatal(a.ctx, "failed to connect to mongo", "error", err)
 	}
 
 	ar := repo.NewAppRepo(md)
 	ur := repo.NewUserRepo(md)
+	cr := repo.NewCursorRepo(md)
 
 	cfb := cfbatch.NewCFBatchApi(cfBatchUrl, cfBatchToken)
+	defer cfb.Stop()
 
-	endpoint, err := resolveIPPAndPort(wgEndpoint)
+	endpoint, err := ResolveIPPAndPort(wgEndpoint)
 	if err != nil {
-		log.Fatal(err)
+		a.logger.Fatal(a.ctx, "failed to resolve wireguard endpoint", "error", err)
 	}
 
 	index := primitive.NilObjectID
+	cursorLoaded := false
 
 	for {
+		// roundCtx tags every log line for this round with the same
+		// trace_id, so a single user-claim failure can be correlated
+		// across the dialer, proxy selection, and the CF batch response.
+		roundCtx := obs.WithTraceID(a.ctx, obs.NewTraceID())
+
 		select {
 		case <-a.ctx.Done():
-			log.Info("context cancelled, exiting loop")
+			a.logger.Info(roundCtx, "context cancelled, exiting loop")
 			return
 		default:
 		}
 
 		dialer, err := NewWGDialer(wgPrivateKey, endpoint)
 		if err != nil {
-			log.Fatal(err)
+			a.logger.Error(roundCtx, "failed to bring up wireguard dialer, backing off", "error", err)
+			a.roundBackoff(roundCtx)
+			continue
 		}
 
 		cfb.SetDialContext(dialer.WireDialer.tnet.DialContext)
 
 		apps, err := ar.GetClaimAppCodes(a.ctx)
 		if err != nil {
-			log.Fatal(err)
+			a.logger.Error(roundCtx, "failed to get claim app codes, backing off", "error", err)
+			dialer.WireDialer.Device.Close()
+			a.roundBackoff(roundCtx)
+			continue
+		}
+
+		appsHash := repo.HashAppCodes(apps)
+
+		if !cursorLoaded {
+			resumed, err := cr.LoadCursor(a.ctx, cursorJobClaim, appsHash)
+			if err != nil {
+				a.logger.Error(roundCtx, "failed to load claim cursor, starting from zero", "error", err)
+			} else if resumed != primitive.NilObjectID {
+				index = resumed
+				a.logger.Info(roundCtx, "resumed claim cursor", "index", index.Hex())
+			}
+			cursorLoaded = true
 		}
 
 		users, err := ur.GetClaimUsers(a.ctx, apps, 120, index)
 		if err != nil {
-			log.Fatal(err)
+			a.logger.Error(roundCtx, "failed to get claim users, backing off", "error", err)
+			dialer.WireDialer.Device.Close()
+			a.roundBackoff(roundCtx)
+			continue
 		}
 
-		log.Info("got users", "total", len(users))
+		a.logger.Info(roundCtx, "got users", "total", len(users))
 
 		if len(users) == 0 {
 			index = primitive.NilObjectID
+			if err := cr.SaveCursor(a.ctx, cursorJobClaim, index, appsHash); err != nil {
+				a.logger.Error(roundCtx, "failed to reset claim cursor", "error", err)
+			}
+			dialer.WireDialer.Device.Close()
 			continue
 		}
 
 		index = users[len(users)-1].ID
 
+		if err := cr.SaveCursor(a.ctx, cursorJobClaim, index, appsHash); err != nil {
+			a.logger.Error(roundCtx, "failed to checkpoint claim cursor", "error", err)
+		}
+
 		// Split users into chunks of 25
 		userChunks := make([][]*repo.ModelUser, 0)
 		chunkSize := 10
@@ -93,20 +136,23 @@ func (a *BatchApp) Run() {
 			userChunks = append(userChunks, users[i:end])
 		}
 
-		log.Info("split into", "total", len(userChunks), "chunk", chunkSize)
+		a.logger.Info(roundCtx, "split into", "total", len(userChunks), "chunk", chunkSize)
 
 		// Use semaphore to limit concurrent goroutines
 		sem := make(chan struct{}, 10) // Limit to 10 concurrent goroutines
 		var wg sync.WaitGroup
 
-		for _, chunk := range userChunks {
+		for chunkIndex, chunk := range userChunks {
 			wg.Add(1)
 
-			go func(userChunk []*repo.ModelUser) {
+			go func(chunkIndex int, userChunk []*repo.ModelUser) {
 				defer wg.Done()
 				sem <- struct{}{}        // Acquire semaphore
 				defer func() { <-sem }() // Release semaphore
 
+				start := time.Now()
+				a.logger.Debug(roundCtx, "chunk worker started", "chunk_index", chunkIndex, "chunk_size", len(userChunk))
+
 				// Convert chunk to CFBatchUser
 				var usersToClaim []cfbatch.CFBatchUser
 				for _, user := range userChunk {
@@ -123,8 +169,12 @@ func (a *BatchApp) Run() {
 					usersToClaim = append(usersToClaim, userClaim)
 				}
 
-				cfb.SendBatch(a.ctx, usersToClaim)
-			}(chunk)
+				if err := cfb.SendBatch(roundCtx, usersToClaim); err != nil {
+					a.logger.Error(roundCtx, "chunk worker failed", "chunk_index", chunkIndex, "error", err)
+				}
+
+				a.logger.Info(roundCtx, "chunk worker done", "chunk_index", chunkIndex, "duration_ms", time.Since(start).Milliseconds())
+			}(chunkIndex, chunk)
 		}
 
 		// Wait for all goroutines to complete
@@ -133,3 +183,15 @@ func (a *BatchApp) Run() {
 		dialer.WireDialer.Device.Close()
 	}
 }
+
+// roundBackoff delays the next round after a failed setup step (wireguard
+// dialer, app codes, claim users), instead of the log.Fatal this used to
+// be: a transient mongo or wireguard hiccup shouldn't kill the whole
+// process. It returns early if ctx is cancelled first.
+func (a *BatchApp) roundBackoff(ctx context.Context) {
+	const delay = 5 * time.Second
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+}
@@ -73,21 +73,61 @@ func (keys ConfigurationKeys) has(key string) bool {
 
 const DEFAULT_MTU = 1420 // MTU is not typically present in WireGuard config files, so a default is provided
 
+// PeerConfig is one parsed [Peer] section.
+type PeerConfig struct {
+	PublicKey           string // hex-encoded
+	AllowedIPs          []string
+	Endpoint            string
+	PresharedKey        string // hex-encoded, empty if the peer didn't set one
+	PersistentKeepalive int    // seconds, 0 if the peer didn't set one
+}
+
 // This function reads a configuration and returns the following parsed values:
 // - ifaceAddresses: IP addresses with which to configure the local WireGuard interface
 // - dnsAddresses: The DNS server to be used by the local WireGuard interface
 // - mtu: MTU to be configured for the local WireGuard interface
 // - ipcConfig: a string that can be used to configure the WireGuard UAPI via the IPC socket
 // If the configuration file is incomplete, e.g. it is missing any fields mandatory for starting the tunnel, an error is returned
-// At the moment, only one [Interface] and one [Peer] section is supported, as that is the most common use case
+// At the moment, only one [Interface] section is supported. ParseConfig itself only
+// accepts a single [Peer] section too; use ParseConfigMulti for split-tunnel configs
+// with more than one upstream relay.
 func ParseConfig(config io.Reader) (ifaceAddresses, dnsAddresses []netip.Addr, mtu int, ipcConfig string, err error) {
-	var privateKeyPresent, publicKeyPresent, endpointPresent, allowedIpsPresent bool
-	var interfaceCount, peerCount int
+	ifaceAddresses, dnsAddresses, mtu, peers, ipcConfig, err := ParseConfigMulti(config)
+	if err != nil {
+		return nil, nil, -1, "", err
+	}
+	if len(peers) > 1 {
+		return nil, nil, -1, "", errors.New("Only one [Peer] section is supported at the moment")
+	}
+	return ifaceAddresses, dnsAddresses, mtu, ipcConfig, nil
+}
+
+// ParseConfigMulti is ParseConfig's multi-peer counterpart: it accepts any
+// number of [Peer] sections, each becoming one entry of the returned peers
+// plus one repeated public_key=.../allowed_ip=.../endpoint=... block
+// (optionally preshared_key=.../persistent_keepalive_interval=...) in
+// ipcConfig, in WireGuard UAPI's "a public_key line starts a new peer"
+// order. Each peer must have at least PublicKey, AllowedIPs, and Endpoint.
+func ParseConfigMulti(config io.Reader) (ifaceAddresses, dnsAddresses []netip.Addr, mtu int, peers []PeerConfig, ipcConfig string, err error) {
+	var privateKeyHex string
+	var privateKeyPresent bool
+	var interfaceCount int
 	var currentSection ConfigSection = SECTION_NONE
+	var current *PeerConfig
 
 	mtu = DEFAULT_MTU
 
-	var ipcConfigBuilder strings.Builder
+	finishPeer := func() error {
+		if current == nil {
+			return nil
+		}
+		if current.PublicKey == "" || len(current.AllowedIPs) == 0 || current.Endpoint == "" {
+			return fmt.Errorf("peer %d is missing PublicKey, AllowedIPs, or Endpoint", len(peers)+1)
+		}
+		peers = append(peers, *current)
+		current = nil
+		return nil
+	}
 
 	lineScanner := bufio.NewScanner(config)
 
@@ -100,116 +140,134 @@ func ParseConfig(config io.Reader) (ifaceAddresses, dnsAddresses []netip.Addr, m
 		if line == "[Interface]" {
 			interfaceCount++
 			if interfaceCount > 1 {
-				return nil, nil, -1, "", errors.New("Only one [Interface] section is supported at the moment")
+				return nil, nil, -1, nil, "", errors.New("Only one [Interface] section is supported at the moment")
 			}
 			currentSection = SECTION_INTERFACE
 			continue
 		}
 
 		if line == "[Peer]" {
-			peerCount++
-			if peerCount > 1 {
-				return nil, nil, -1, "", errors.New("Only one [Peer] section is supported at the moment")
+			if err := finishPeer(); err != nil {
+				return nil, nil, -1, nil, "", err
 			}
+			current = &PeerConfig{}
 			currentSection = SECTION_PEER
 			continue
 		}
 
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
-			return nil, nil, -1, "", fmt.Errorf("Invalid line in config: %s", lineScanner.Text())
+			return nil, nil, -1, nil, "", fmt.Errorf("Invalid line in config: %s", lineScanner.Text())
 		}
 
 		key := strings.TrimSpace(parts[0])
 		value := strings.TrimSpace(parts[1])
 
 		if !currentSection.IsElementValid(key) {
-			return nil, nil, -1, "", fmt.Errorf("Invalid key %s in section %s", key, currentSection.String())
+			return nil, nil, -1, nil, "", fmt.Errorf("Invalid key %s in section %s", key, currentSection.String())
 		}
 
 		switch key {
 		case "PrivateKey":
-			privateKeyBase64 := value
-
-			privateKeyBytes, err := base64.StdEncoding.DecodeString(privateKeyBase64)
+			privateKeyBytes, err := base64.StdEncoding.DecodeString(value)
 			if err != nil {
-				return nil, nil, -1, "", fmt.Errorf("Error decoding private key: %s", err)
+				return nil, nil, -1, nil, "", fmt.Errorf("Error decoding private key: %s", err)
 			}
-			privateKeyHex := hex.EncodeToString(privateKeyBytes)
-
-			ipcConfigBuilder.WriteString(fmt.Sprintf("private_key=%s\n", privateKeyHex))
+			privateKeyHex = hex.EncodeToString(privateKeyBytes)
 			privateKeyPresent = true
 		case "Address":
 			// split by comma
 			addresses := strings.Split(value, ",")
 			if len(addresses) == 0 {
-				return nil, nil, -1, "", fmt.Errorf("No addresses found in Address field")
+				return nil, nil, -1, nil, "", fmt.Errorf("No addresses found in Address field")
 			}
 			for _, address := range addresses {
 				parsedAddress, err := netip.ParsePrefix(address)
 				if err != nil {
-					return nil, nil, -1, "", fmt.Errorf("Error parsing address: %s", err)
+					return nil, nil, -1, nil, "", fmt.Errorf("Error parsing address: %s", err)
 				}
 				ifaceAddresses = append(ifaceAddresses, parsedAddress.Addr())
 			}
 		case "MTU":
 			mtu, err = strconv.Atoi(value)
 			if err != nil {
-				return nil, nil, -1, "", fmt.Errorf("Error parsing MTU: %s", err)
+				return nil, nil, -1, nil, "", fmt.Errorf("Error parsing MTU: %s", err)
 			}
 		case "DNS":
 			// split by comma
 			addresses := strings.Split(value, ",")
 			if len(addresses) == 0 {
-				return nil, nil, -1, "", fmt.Errorf("No addresses found in DNS field")
+				return nil, nil, -1, nil, "", fmt.Errorf("No addresses found in DNS field")
 			}
 			for _, address := range addresses {
 				parsedAddress, err := netip.ParseAddr(address)
 				if err != nil {
-					return nil, nil, -1, "", fmt.Errorf("Error parsing address: %s", err)
+					return nil, nil, -1, nil, "", fmt.Errorf("Error parsing address: %s", err)
 				}
 				dnsAddresses = append(dnsAddresses, parsedAddress)
 			}
 		case "PublicKey":
-			publicKeyBase64 := value
-
-			publicKeyBytes, err := base64.StdEncoding.DecodeString(publicKeyBase64)
+			publicKeyBytes, err := base64.StdEncoding.DecodeString(value)
 			if err != nil {
-				return nil, nil, -1, "", fmt.Errorf("Error decoding public key: %s", err)
+				return nil, nil, -1, nil, "", fmt.Errorf("Error decoding public key: %s", err)
 			}
-
-			publicKeyHex := hex.EncodeToString(publicKeyBytes)
-
-			ipcConfigBuilder.WriteString(fmt.Sprintf("public_key=%s\n", publicKeyHex))
-			publicKeyPresent = true
+			current.PublicKey = hex.EncodeToString(publicKeyBytes)
 		case "AllowedIPs":
 			// split by comma
 			allowedIps := strings.Split(value, ",")
 			if len(allowedIps) == 0 {
-				return nil, nil, -1, "", fmt.Errorf("No allowed IPs found in AllowedIPs field")
-			}
-
-			for _, allowedIp := range allowedIps {
-				ipcConfigBuilder.WriteString(fmt.Sprintf("allowed_ip=%s\n", allowedIp))
-				allowedIpsPresent = true
+				return nil, nil, -1, nil, "", fmt.Errorf("No allowed IPs found in AllowedIPs field")
 			}
+			current.AllowedIPs = append(current.AllowedIPs, allowedIps...)
 		case "Endpoint":
-			ipcConfigBuilder.WriteString(fmt.Sprintf("endpoint=%s\n", value))
-			endpointPresent = true
+			current.Endpoint = value
+		case "PresharedKey":
+			presharedKeyBytes, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, nil, -1, nil, "", fmt.Errorf("Error decoding preshared key: %s", err)
+			}
+			current.PresharedKey = hex.EncodeToString(presharedKeyBytes)
+		case "PersistentKeepalive":
+			current.PersistentKeepalive, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, nil, -1, nil, "", fmt.Errorf("Error parsing PersistentKeepalive: %s", err)
+			}
 		}
+	}
 
+	if err := finishPeer(); err != nil {
+		return nil, nil, -1, nil, "", err
 	}
 
 	// Determine if we have enough information to start the tunnel
-	minimalConfigPresent := privateKeyPresent && publicKeyPresent && endpointPresent && allowedIpsPresent && len(dnsAddresses) > 0 && len(ifaceAddresses) > 0
+	minimalConfigPresent := privateKeyPresent && len(peers) > 0 && len(dnsAddresses) > 0 && len(ifaceAddresses) > 0
 	if !minimalConfigPresent {
-		return nil, nil, -1, "", fmt.Errorf("Configuration provided is not sufficient.")
+		return nil, nil, -1, nil, "", fmt.Errorf("Configuration provided is not sufficient.")
+	}
+
+	var ipcConfigBuilder strings.Builder
+	ipcConfigBuilder.WriteString(fmt.Sprintf("private_key=%s\n", privateKeyHex))
+	for _, peer := range peers {
+		ipcConfigBuilder.WriteString(fmt.Sprintf("public_key=%s\n", peer.PublicKey))
+		if peer.PresharedKey != "" {
+			ipcConfigBuilder.WriteString(fmt.Sprintf("preshared_key=%s\n", peer.PresharedKey))
+		}
+		for _, allowedIp := range peer.AllowedIPs {
+			ipcConfigBuilder.WriteString(fmt.Sprintf("allowed_ip=%s\n", allowedIp))
+		}
+		ipcConfigBuilder.WriteString(fmt.Sprintf("endpoint=%s\n", peer.Endpoint))
+		if peer.PersistentKeepalive > 0 {
+			ipcConfigBuilder.WriteString(fmt.Sprintf("persistent_keepalive_interval=%d\n", peer.PersistentKeepalive))
+		}
 	}
 
-	return ifaceAddresses, dnsAddresses, mtu, ipcConfigBuilder.String(), nil
+	return ifaceAddresses, dnsAddresses, mtu, peers, ipcConfigBuilder.String(), nil
 }
 
-func resolveIPPAndPort(addr string) (string, error) {
+// ResolveIPPAndPort resolves the host half of addr ("host:port") to an IP
+// address, so a WireGuard endpoint only needs DNS resolved once up front
+// instead of on every handshake retry.
+func ResolveIPPAndPort(addr string) (string, error) {
 	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
 		return "", err
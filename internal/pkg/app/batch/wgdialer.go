@@ -1,9 +1,12 @@
 package batch
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"net"
+	"os"
 	"strings"
 
 	"golang.zx2c4.com/wireguard/conn"
@@ -36,9 +39,10 @@ func NewDialerFromConfiguration(config_reader io.Reader) (*WireDialer, error) {
 		log.Panic(err)
 	}
 	dev := device.NewDevice(tun, conn.NewDefaultBind(), device.NewLogger(device.LogLevelError, ""))
-	err = dev.IpcSet(ipcConfig)
-	err = dev.Up()
-	if err != nil {
+	if err := dev.IpcSet(ipcConfig); err != nil {
+		log.Panic(err)
+	}
+	if err := dev.Up(); err != nil {
 		log.Panic(err)
 	}
 
@@ -62,3 +66,48 @@ func NewWGDialer(wgPrivateKey, wgEndpoint string) (*WGDialer, error) {
 		WireDialer: d,
 	}, nil
 }
+
+// DialContext dials addr over the tunnel's userspace network stack, so a
+// WireDialer can be plugged straight into an http.Transport or a req.Client's
+// DialContext field as the egress for HTTP traffic.
+func (w *WireDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return w.tnet.DialContext(ctx, network, addr)
+}
+
+// NewDialerFromConfigFile reads a full WireGuard config file from path,
+// unlike NewWGDialer which fills VALID_CONFIG's fixed peer/address/DNS
+// template around just a private key and endpoint. It resolves the config's
+// Endpoint line once via ResolveIPPAndPort, so DNS isn't redone on every
+// handshake retry, and brings up the resulting tunnel.
+func NewDialerFromConfigFile(path string) (*WireDialer, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WireGuard config %s: %w", path, err)
+	}
+
+	resolved, err := resolveConfigEndpoint(string(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDialerFromConfiguration(strings.NewReader(resolved))
+}
+
+// resolveConfigEndpoint rewrites a config's "Endpoint=host:port" line, if
+// present, to "Endpoint=ip:port".
+func resolveConfigEndpoint(raw string) (string, error) {
+	lines := strings.Split(raw, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "Endpoint=") {
+			continue
+		}
+
+		resolved, err := ResolveIPPAndPort(strings.TrimPrefix(trimmed, "Endpoint="))
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve WireGuard endpoint: %w", err)
+		}
+		lines[i] = "Endpoint=" + resolved
+	}
+	return strings.Join(lines, "\n"), nil
+}
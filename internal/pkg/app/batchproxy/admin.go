@@ -0,0 +1,142 @@
+package batchproxy
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/goccy/go-yaml"
+	"github.com/gorilla/websocket"
+
+	"dayusch/internal/pkg/admin"
+	cfbatch "dayusch/internal/pkg/api/cfbatch/v2"
+	"dayusch/internal/pkg/api/yarun"
+)
+
+// AdminConfig is only settable from a YAML config file (-input), like
+// Config.Wireguard: there's no sensible single env var for a
+// username/password pair plus a listen address.
+type AdminConfig struct {
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// statusResponse is /api/status's body.
+type statusResponse struct {
+	MaxConcurrent int            `json:"max_concurrent"`
+	DelaySeconds  int            `json:"delay_seconds"`
+	UptimeSeconds float64        `json:"uptime_seconds"`
+	Workers       []workerStatus `json:"workers"`
+}
+
+// newAdminServer builds the admin server's handlers over state:
+// /api/status and /api/reload as before, plus /ws/logs and /ws/events
+// for live WebSocket tailing of state.logBus. inputFile is the YAML
+// config path /api/reload re-reads; it's empty when Run was configured
+// from environment variables, in which case reload always fails
+// (there's nothing on disk to re-read).
+func newAdminServer(config AdminConfig, inputFile string, state *runtimeState) *admin.Server {
+	srv := admin.NewServer(config.Username, config.Password)
+
+	srv.Handle("/api/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		admin.WriteJSON(w, statusResponse{
+			MaxConcurrent: state.getMaxConcurrent(),
+			DelaySeconds:  int(state.getDelay() / time.Second),
+			UptimeSeconds: time.Since(state.startedAt).Seconds(),
+			Workers:       state.snapshotWorkers(),
+		})
+	})
+
+	srv.Handle("/api/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := reloadFromFile(inputFile, state); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		admin.WriteJSON(w, map[string]bool{"ok": true})
+	})
+
+	// /ws/logs?worker=<id> streams every record tagged with that worker
+	// ID, lifecycle events and plain log lines alike; omitting worker
+	// streams every worker unfiltered.
+	srv.HandleWS("/ws/logs", func(conn *websocket.Conn, r *http.Request) {
+		var workerFilter *int
+		if v := r.URL.Query().Get("worker"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				conn.WriteJSON(map[string]string{"error": "invalid worker id"})
+				return
+			}
+			workerFilter = &id
+		}
+
+		ch, unsubscribe := state.logBus.Subscribe()
+		defer unsubscribe()
+
+		admin.StreamLogRecords(conn, ch, func(rec admin.LogRecord) bool {
+			return workerFilter == nil || rec.WorkerID == *workerFilter
+		})
+	})
+
+	// /ws/events streams only lifecycle events (worker_started,
+	// worker_finished, batch_failed, proxy_blocked), across all
+	// workers, filtering out the plain log lines /ws/logs also carries.
+	srv.HandleWS("/ws/events", func(conn *websocket.Conn, r *http.Request) {
+		ch, unsubscribe := state.logBus.Subscribe()
+		defer unsubscribe()
+
+		admin.StreamLogRecords(conn, ch, func(rec admin.LogRecord) bool {
+			return rec.Type != ""
+		})
+	})
+
+	return srv
+}
+
+// reloadFromFile re-reads inputFile and applies its max concurrency,
+// delay, and credentials to state. Workers already in flight keep
+// running against whatever they cloned before the swap; only the next
+// round picks up the new limits and clients.
+func reloadFromFile(inputFile string, state *runtimeState) error {
+	if inputFile == "" {
+		return fmt.Errorf("reload requires batchproxy to have been started with -input")
+	}
+
+	data, err := os.ReadFile(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse YAML config: %w", err)
+	}
+
+	if config.BaseURL == "" || config.Token == "" || config.ProxyUsername == "" || config.ProxyPassword == "" || config.YarunBaseURL == "" || config.YarunToken == "" {
+		return fmt.Errorf("missing required configuration: base_url, token, proxy_username, proxy_password, yarun_base_url, yarun_token")
+	}
+
+	state.setCredentials(credentials{
+		parentApi:     cfbatch.NewCFBatchApi(config.BaseURL, config.Token),
+		yarunClient:   yarun.NewYarunApi(config.YarunBaseURL, config.YarunToken),
+		proxyUsername: config.ProxyUsername,
+		proxyPassword: config.ProxyPassword,
+	})
+	if config.MaxConcurrent > 0 {
+		state.setLimits(config.MaxConcurrent, config.Delay)
+	}
+
+	log.Info("Reloaded batchproxy config from file", "file", inputFile)
+	return nil
+}
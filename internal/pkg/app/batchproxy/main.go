@@ -2,21 +2,23 @@ package batchproxy
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
-	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/goccy/go-yaml"
-	"golang.org/x/sync/semaphore"
 
 	cfbatch "dayusch/internal/pkg/api/cfbatch/v2"
 	"dayusch/internal/pkg/api/yarun"
 	"dayusch/internal/pkg/helper"
+	"dayusch/internal/pkg/pool"
+	"dayusch/internal/pkg/progress"
 )
 
 type Config struct {
@@ -26,9 +28,25 @@ type Config struct {
 	ProxyPassword string `yaml:"proxy_password"`
 	YarunBaseURL  string `yaml:"yarun_base_url"`
 	YarunToken    string `yaml:"yarun_token"`
+	// Wireguard is only settable from a YAML config file (-input), not the
+	// env var fallback below: a list of tunnels doesn't fit in a single
+	// env var the way the other fields do. When set, workers dial CFBatch
+	// over a tunnel from the pool before (optionally) going through the
+	// HTTP proxy set by proxy_username/proxy_password.
+	Wireguard *WireguardConfig `yaml:"wireguard"`
+	// Admin is only settable from a YAML config file, like Wireguard
+	// above. When set, Run starts a basic-auth-protected admin server
+	// exposing /api/status and /api/reload.
+	Admin *AdminConfig `yaml:"admin"`
+	// MaxConcurrent and Delay mirror the -concurrent/-delay flags, but
+	// only take effect via /api/reload re-reading this file; the initial
+	// round always uses Run's maxConcurrent/delay parameters. A zero
+	// MaxConcurrent means "not overridden by this file".
+	MaxConcurrent uint `yaml:"max_concurrent"`
+	Delay         uint `yaml:"delay"`
 }
 
-func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
+func Run(maxConcurrent, batchLimit, delay uint, inputFile string, noProgress bool) {
 	var config Config
 
 	// Create a root context that will be cancelled on shutdown
@@ -80,14 +98,51 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 		"delay", delay,
 	)
 
-	// Create parent CFBatchApi
-	parentApi := cfbatch.NewCFBatchApi(config.BaseURL, config.Token)
+	// Create parent CFBatchApi and yarun API client, held behind a
+	// runtimeState so /api/reload can swap in a fresh pair without
+	// disturbing workers already cloned from the old one.
+	state := newRuntimeState(maxConcurrent, delay, credentials{
+		parentApi:     cfbatch.NewCFBatchApi(config.BaseURL, config.Token),
+		yarunClient:   yarun.NewYarunApi(config.YarunBaseURL, config.YarunToken),
+		proxyUsername: config.ProxyUsername,
+		proxyPassword: config.ProxyPassword,
+	})
+	defer func() {
+		creds := state.getCredentials()
+		creds.parentApi.Stop()
+		creds.yarunClient.Stop()
+	}()
+
+	if config.Admin != nil && config.Admin.Addr != "" {
+		adminServer := newAdminServer(*config.Admin, inputFile, state)
+		go func() {
+			log.Info("Starting batchproxy admin server", "addr", config.Admin.Addr)
+			if err := adminServer.ListenAndServe(config.Admin.Addr); err != nil && err != http.ErrServerClosed {
+				log.Error("Admin server stopped", "error", err)
+			}
+		}()
+		go func() {
+			<-rootCtx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			adminServer.Shutdown(shutdownCtx)
+		}()
+	}
 
-	// Create yarun API client
-	yarunClient := yarun.NewYarunApi(config.YarunBaseURL, config.YarunToken)
+	var wgPool *wgTunnelPool
+	if config.Wireguard != nil {
+		var err error
+		wgPool, err = newWGTunnelPool(config.Wireguard)
+		if err != nil {
+			log.Fatal("Failed to bring up wireguard tunnel pool", "error", err)
+		}
+		defer wgPool.close()
+		log.Info("Wireguard tunnel pool ready", "tunnels", len(config.Wireguard.Peers), "rotation", config.Wireguard.Rotation)
+	}
 
 	log.Info("Created parent CFBatchApi and yarun client instances")
 
+	round := 0
 	for {
 		// Check for shutdown signal
 		select {
@@ -97,11 +152,16 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 		default:
 		}
 
-		log.Info("Starting new batch round")
+		round++
+		maxConcurrent := state.getMaxConcurrent()
+		delay := state.getDelay()
+		creds := state.getCredentials()
+
+		log.Info("Starting new batch round", "round", round)
 
 		// Get available proxies from yarun
 		ctx, cancel := context.WithTimeout(rootCtx, 30*time.Second)
-		proxiesResp, err := yarunClient.GetProxies(ctx, int(maxConcurrent))
+		proxiesResp, err := creds.yarunClient.GetProxies(ctx, maxConcurrent)
 		cancel()
 
 		if err != nil {
@@ -109,14 +169,18 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 				log.Info("Proxy request cancelled due to shutdown")
 				return
 			}
-			log.Error("Failed to get proxies from yarun", "error", err)
+			if errors.Is(err, yarun.ErrUpstreamDown) {
+				log.Warn("yarun upstream is down, skipping round", "round", round)
+			} else {
+				log.Error("Failed to get proxies from yarun", "error", err)
+			}
 
 			// Check for shutdown before sleeping
 			select {
 			case <-rootCtx.Done():
 				log.Info("Shutdown requested during delay")
 				return
-			case <-time.After(time.Duration(delay) * time.Second):
+			case <-time.After(delay):
 			}
 			continue
 		}
@@ -129,65 +193,71 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 			case <-rootCtx.Done():
 				log.Info("Shutdown requested during delay")
 				return
-			case <-time.After(time.Duration(delay) * time.Second):
+			case <-time.After(delay):
 			}
 			continue
 		}
 
 		log.Info("Got proxies from yarun", "count", len(proxiesResp.Proxies))
 
-		// Create semaphore for controlling concurrency
-		sem := semaphore.NewWeighted(int64(maxConcurrent))
-		var wg sync.WaitGroup
+		// Bound worker concurrency to maxConcurrent via the shared pool.
+		workers, poolCtx := pool.New(rootCtx, maxConcurrent)
+		bar := progress.New("Processing proxies", len(proxiesResp.Proxies), progress.WithSilent(noProgress))
+		workers.OnProgress(len(proxiesResp.Proxies), bar.Update)
 
 		// Create concurrent workers using available proxies
 		for i, proxy := range proxiesResp.Proxies {
-			if i >= int(maxConcurrent) {
+			if i >= maxConcurrent {
 				break // Don't exceed maxConcurrent
 			}
 
-			wg.Add(1)
-			go func(workerID int, proxy yarun.ProxyResponse) {
-				defer func() {
-					log.Info("Worker finished", "workerID", workerID, "assignedPort", proxy.Port)
-					wg.Done()
-				}()
+			workerID, proxy, round := i, proxy, round
+			startedAt := time.Now()
+			state.setWorker(workerStatus{ID: workerID, ProxyID: proxy.ID, ProxyPort: proxy.Port, BatchIndex: round, StartedAt: startedAt})
+			workers.Go(func() error {
+				defer state.clearWorker(workerID)
+				defer log.Info("Worker finished", "workerID", workerID, "assignedPort", proxy.Port)
+				defer state.publish(workerID, "worker_finished", fmt.Sprintf("assigned port %d", proxy.Port))
 
 				// Add timeout for entire worker
-				workerCtx, workerCancel := context.WithTimeout(rootCtx, 10*time.Minute)
+				workerCtx, workerCancel := context.WithTimeout(poolCtx, 10*time.Minute)
 				defer workerCancel()
 
 				// Check for early cancellation
 				select {
 				case <-workerCtx.Done():
 					log.Warn("Worker cancelled before starting", "workerID", workerID)
-					return
+					return nil
 				default:
 				}
 
-				// Acquire semaphore
-				if err := sem.Acquire(context.Background(), 1); err != nil {
-					log.Error("Failed to acquire semaphore", "workerID", workerID, "error", err)
-					return
-				}
-				defer sem.Release(1)
-
 				log.Info("Worker started", "workerID", workerID, "assignedPort", proxy.Port)
+				state.publish(workerID, "worker_started", fmt.Sprintf("assigned port %d", proxy.Port))
 
 				// Send batch request with worker context
 				ctx, cancel := context.WithTimeout(workerCtx, 60*time.Second)
 				defer cancel()
 
-				api := parentApi.Clone()
+				api := creds.parentApi.Clone()
 
 				// Set user agent first (round-robin)
 				userAgent := helper.GetNextUserAgent()
 				api.SetUserAgent(userAgent)
 
 				// Then set proxy URL
-				proxyURL := fmt.Sprintf("http://%s:%s@gw.dataimpulse.com:%d", config.ProxyUsername, config.ProxyPassword, proxy.Port)
+				proxyURL := fmt.Sprintf("http://%s:%s@gw.dataimpulse.com:%d", creds.proxyUsername, creds.proxyPassword, proxy.Port)
 				api.SetProxyURL(proxyURL)
 
+				// If a wireguard pool is configured, dial through a tunnel
+				// first; SetProxyURL above still controls the HTTP CONNECT
+				// made over that tunnel.
+				var tunnel *wgTunnel
+				if wgPool != nil {
+					tunnel = wgPool.pick()
+					api.SetDialContext(tunnel.dialer.DialContext)
+					log.Info("Wireguard tunnel assigned", "workerID", workerID)
+				}
+
 				log.Info("Proxy and User-Agent configured", "workerID", workerID, "port", proxy.Port, "userAgent", userAgent[:16]+"...")
 
 				responses, err := api.SendBatch(ctx, int(batchLimit))
@@ -196,14 +266,21 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 				if err != nil {
 					if ctx.Err() == context.Canceled {
 						log.Info("Batch request cancelled due to shutdown", "workerID", workerID)
-						return
+						return nil
 					}
 					if ctx.Err() == context.DeadlineExceeded {
 						log.Warn("Batch request timeout", "workerID", workerID, "port", proxy.Port, "timeout", "60s")
 					}
 					log.Error("SendBatch failed", "workerID", workerID, "port", proxy.Port, "error", err)
+					state.publish(workerID, "batch_failed", err.Error())
 					shouldBlockProxy = true
+					if tunnel != nil {
+						wgPool.reportFailure(tunnel)
+					}
 				} else {
+					if tunnel != nil {
+						wgPool.reportSuccess(tunnel)
+					}
 					log.Info("SendBatch completed successfully",
 						"workerID", workerID,
 						"limit", batchLimit,
@@ -213,17 +290,15 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 					var failedCount int32
 					totalCount := len(responses)
 
-					// Process each response concurrently using goroutines
-					var responseWg sync.WaitGroup
-					responseSem := semaphore.NewWeighted(int64(len(responses))) // Allow all responses to run concurrently
+					// Process each response concurrently; unbounded, since
+					// every response just logs and tallies an in-memory counter.
+					responseWorkers, responsePoolCtx := pool.New(workerCtx, 0)
 
 					for i, response := range responses {
-						responseWg.Add(1)
-						go func(idx int, resp cfbatch.BatchResponse) {
-							defer responseWg.Done()
-
+						idx, resp := i, response
+						responseWorkers.Go(func() error {
 							// Add timeout for entire response processing
-							responseCtx, responseCancel := context.WithTimeout(workerCtx, 2*time.Minute)
+							responseCtx, responseCancel := context.WithTimeout(responsePoolCtx, 2*time.Minute)
 							defer responseCancel()
 
 							// Check for shutdown signal
@@ -232,21 +307,10 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 								if responseCtx.Err() == context.DeadlineExceeded {
 									log.Warn("Response processing timeout", "responseIndex", idx, "workerID", workerID)
 								}
-								return
+								return nil
 							default:
 							}
 
-							// Acquire semaphore for this response processing
-							if err := responseSem.Acquire(responseCtx, 1); err != nil {
-								if err == context.Canceled || err == context.DeadlineExceeded {
-									log.Info("Response processing cancelled or timed out")
-									return
-								}
-								log.Error("Failed to acquire response semaphore", "error", err)
-								return
-							}
-							defer responseSem.Release(1)
-
 							if !resp.Status {
 								atomic.AddInt32(&failedCount, 1)
 							}
@@ -270,12 +334,14 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 									"b", "nil",
 									"c", "nil")
 							}
-						}(i, response)
+
+							return nil
+						})
 					}
 
 					// Wait for all response processing to complete
 					log.Info("Waiting for all response processing to complete", "totalResponses", totalCount)
-					responseWg.Wait()
+					responseWorkers.Wait()
 					log.Info("All response processing completed")
 
 					// Check if failure rate is >= 50%
@@ -295,37 +361,41 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 								"port", proxy.Port,
 								"failureRate", fmt.Sprintf("%.2f%%", failureRate*100))
 						}
+
+						state.setWorker(workerStatus{ID: workerID, ProxyID: proxy.ID, ProxyPort: proxy.Port, BatchIndex: round, LastFailureRate: failureRate, StartedAt: startedAt})
 					}
 				}
 
 				// Block proxy if needed (either due to API error or high failure rate)
 				if shouldBlockProxy {
 					blockCtx, blockCancel := context.WithTimeout(workerCtx, 30*time.Second)
-					_, blockErr := yarunClient.BlockProxy(blockCtx, proxy.ID)
+					_, blockErr := creds.yarunClient.BlockProxy(blockCtx, proxy.ID)
 					blockCancel()
 
 					if blockErr != nil {
 						if blockCtx.Err() == context.Canceled || blockCtx.Err() == context.DeadlineExceeded {
 							log.Info("Block proxy cancelled or timed out", "workerID", workerID)
-							return
+							return nil
 						}
 						log.Error("Failed to block proxy", "workerID", workerID, "port", proxy.Port, "error", blockErr)
 					} else {
-						log.Info("Proxy blocked", "workerID", workerID, "port", proxy.Port, "reason", func() string {
-							if err != nil {
-								return "API error"
-							}
-							return "high failure rate (>=50%)"
-						}())
+						reason := "high failure rate (>=50%)"
+						if err != nil {
+							reason = "API error"
+						}
+						log.Info("Proxy blocked", "workerID", workerID, "port", proxy.Port, "reason", reason)
+						state.publish(workerID, "proxy_blocked", reason)
 					}
 				}
-			}(i, proxy)
+
+				return nil
+			})
 		}
 
 		// Wait for all workers to complete with timeout monitoring
 		workersDone := make(chan struct{})
 		go func() {
-			wg.Wait()
+			workers.Wait()
 			close(workersDone)
 		}()
 
@@ -339,11 +409,13 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 			select {
 			case <-workersDone:
 				log.Info("All workers completed, starting next round")
+				bar.Finish()
 				goto nextRound
 			case <-ticker.C:
 				log.Info("Still waiting for workers to complete...")
 			case <-rootCtx.Done():
 				log.Info("Shutdown requested while waiting for workers")
+				bar.Finish()
 				return
 			}
 		}
@@ -355,7 +427,7 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 		case <-rootCtx.Done():
 			log.Info("Shutdown requested, exiting main loop")
 			return
-		case <-time.After(time.Duration(delay) * time.Second):
+		case <-time.After(delay):
 		}
 	}
 }
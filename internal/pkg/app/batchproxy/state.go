@@ -0,0 +1,136 @@
+package batchproxy
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dayusch/internal/pkg/admin"
+	cfbatch "dayusch/internal/pkg/api/cfbatch/v2"
+	"dayusch/internal/pkg/api/yarun"
+)
+
+// workerStatus is a point-in-time view of one in-flight worker,
+// returned by the admin server's /api/status.
+type workerStatus struct {
+	ID              int       `json:"id"`
+	ProxyID         string    `json:"proxy_id"`
+	ProxyPort       int       `json:"proxy_port"`
+	BatchIndex      int       `json:"batch_index"`
+	LastFailureRate float64   `json:"last_failure_rate"`
+	StartedAt       time.Time `json:"started_at"`
+}
+
+// credentials bundles everything /api/reload can replace: the two API
+// clients (whose construction already re-parses their auth
+// descriptors) plus the plain proxy_username/proxy_password pair used
+// to build each worker's proxy URL.
+type credentials struct {
+	parentApi     *cfbatch.CFBatchApi
+	yarunClient   *yarun.YarunApi
+	proxyUsername string
+	proxyPassword string
+}
+
+// runtimeState is batchproxy.Run's mutable state: the credentials and
+// concurrency/delay settings /api/reload can swap live, plus the set
+// of currently-running workers /api/status reports. A single instance
+// is shared between Run's main loop and the admin server's handlers.
+type runtimeState struct {
+	startedAt time.Time
+
+	maxConcurrent atomic.Uint64
+	delaySeconds  atomic.Uint64
+
+	credsMu sync.RWMutex
+	creds   credentials
+
+	workersMu sync.Mutex
+	workers   map[int]*workerStatus
+
+	// logBus carries both plain worker-tagged log lines and lifecycle
+	// events (worker started/finished, proxy blocked) out to the admin
+	// server's /ws/logs and /ws/events subscribers.
+	logBus *admin.LogBus
+}
+
+func newRuntimeState(maxConcurrent, delay uint, creds credentials) *runtimeState {
+	s := &runtimeState{
+		startedAt: time.Now(),
+		creds:     creds,
+		workers:   make(map[int]*workerStatus),
+		logBus:    admin.NewLogBus(),
+	}
+	s.maxConcurrent.Store(uint64(maxConcurrent))
+	s.delaySeconds.Store(uint64(delay))
+	return s
+}
+
+func (s *runtimeState) getMaxConcurrent() int {
+	return int(s.maxConcurrent.Load())
+}
+
+func (s *runtimeState) getDelay() time.Duration {
+	return time.Duration(s.delaySeconds.Load()) * time.Second
+}
+
+func (s *runtimeState) setLimits(maxConcurrent, delay uint) {
+	s.maxConcurrent.Store(uint64(maxConcurrent))
+	s.delaySeconds.Store(uint64(delay))
+}
+
+func (s *runtimeState) getCredentials() credentials {
+	s.credsMu.RLock()
+	defer s.credsMu.RUnlock()
+	return s.creds
+}
+
+// setCredentials swaps in a freshly-built pair, stopping the old
+// parentApi/yarunClient's auth backends. Workers already cloned from
+// the old parentApi keep running against it until they finish their
+// current round; only the parent clients themselves are stopped here.
+func (s *runtimeState) setCredentials(creds credentials) {
+	s.credsMu.Lock()
+	old := s.creds
+	s.creds = creds
+	s.credsMu.Unlock()
+
+	old.parentApi.Stop()
+	old.yarunClient.Stop()
+}
+
+func (s *runtimeState) setWorker(status workerStatus) {
+	s.workersMu.Lock()
+	defer s.workersMu.Unlock()
+	s.workers[status.ID] = &status
+}
+
+func (s *runtimeState) clearWorker(id int) {
+	s.workersMu.Lock()
+	defer s.workersMu.Unlock()
+	delete(s.workers, id)
+}
+
+// publish tags a record with workerID and an optional lifecycle event
+// type and sends it to logBus. Passing eventType == "" marks it as a
+// plain log line, visible on /ws/logs?worker=<id> but filtered out of
+// /ws/events.
+func (s *runtimeState) publish(workerID int, eventType, message string) {
+	s.logBus.Publish(admin.LogRecord{
+		Timestamp: time.Now(),
+		Type:      eventType,
+		WorkerID:  workerID,
+		Message:   message,
+	})
+}
+
+func (s *runtimeState) snapshotWorkers() []workerStatus {
+	s.workersMu.Lock()
+	defer s.workersMu.Unlock()
+
+	out := make([]workerStatus, 0, len(s.workers))
+	for _, w := range s.workers {
+		out = append(out, *w)
+	}
+	return out
+}
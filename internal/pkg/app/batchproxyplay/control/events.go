@@ -0,0 +1,53 @@
+package control
+
+import "sync"
+
+// EventBus fans out Events to any number of subscribers. A slow or
+// absent subscriber never blocks a publisher: Publish drops the event
+// for that subscriber instead of waiting, so Server's GET /events
+// stream never holds up a publisher.
+type EventBus struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+// NewEventBus returns an empty EventBus, ready to use.
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subs: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe registers a new channel and returns it along with an
+// unsubscribe func the caller must run once done reading from it.
+func (b *EventBus) Subscribe() (ch chan Event, unsubscribe func()) {
+	ch = make(chan Event, 32)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// Publish delivers ev to every current subscriber, dropping it for any
+// subscriber whose buffer is full rather than blocking.
+func (b *EventBus) Publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
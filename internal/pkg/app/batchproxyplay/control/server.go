@@ -0,0 +1,210 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// Server exposes a Controller and its EventBus over plain HTTP/JSON.
+type Server struct {
+	controller Controller
+	events     *EventBus
+	httpServer *http.Server
+}
+
+// NewServer builds a Server for controller, with events streamed to
+// GET /events coming from bus.
+func NewServer(controller Controller, bus *EventBus) *Server {
+	s := &Server{controller: controller, events: bus}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/workers", s.handleWorkers)
+	mux.HandleFunc("/workers/", s.handleWorkerAction)
+	mux.HandleFunc("/proxies/", s.handleProxyAction)
+	mux.HandleFunc("/reload", s.handleReload)
+	mux.HandleFunc("/drain", s.handleDrain)
+	mux.HandleFunc("/events", s.handleEvents)
+
+	s.httpServer = &http.Server{Handler: mux}
+	return s
+}
+
+// ListenAndServe blocks serving on addr until the server is shut down.
+func (s *Server) ListenAndServe(addr string) error {
+	s.httpServer.Addr = addr
+	return s.httpServer.ListenAndServe()
+}
+
+// Shutdown gracefully stops the server, waiting out in-flight requests
+// (including any open /events streams) until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func (s *Server) handleWorkers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, s.controller.ListWorkers())
+}
+
+// handleWorkerAction handles POST /workers/{id}/pause and
+// POST /workers/{id}/resume.
+func (s *Server) handleWorkerAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, action, ok := splitTrailingAction(r.URL.Path, "/workers/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	workerID, err := strconv.Atoi(id)
+	if err != nil {
+		http.Error(w, "invalid worker id", http.StatusBadRequest)
+		return
+	}
+
+	switch action {
+	case "pause":
+		err = s.controller.PauseWorker(workerID)
+	case "resume":
+		err = s.controller.ResumeWorker(workerID)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, map[string]bool{"ok": true})
+}
+
+// handleProxyAction handles POST /proxies/{id}/block and
+// POST /proxies/{id}/unblock.
+func (s *Server) handleProxyAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	proxyID, action, ok := splitTrailingAction(r.URL.Path, "/proxies/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var err error
+	switch action {
+	case "block":
+		err = s.controller.BlockProxy(proxyID)
+	case "unblock":
+		err = s.controller.UnblockProxy(proxyID)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, map[string]bool{"ok": true})
+}
+
+type reloadRequest struct {
+	BaseURL      string `json:"base_url"`
+	Token        string `json:"token"`
+	YarunBaseURL string `json:"yarun_base_url"`
+	YarunToken   string `json:"yarun_token"`
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req reloadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.controller.ReloadConfig(req.BaseURL, req.Token, req.YarunBaseURL, req.YarunToken); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, map[string]bool{"ok": true})
+}
+
+func (s *Server) handleDrain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.controller.Drain()
+	writeJSON(w, map[string]bool{"ok": true})
+}
+
+// handleEvents streams Events as newline-delimited JSON for as long as
+// the client stays connected, the same JSONL-over-a-flushed-writer
+// convention checker.CheckDomains already uses for its per-domain results.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := encoder.Encode(ev); err != nil {
+				log.Warn("Failed to encode event to stream", "error", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// splitTrailingAction splits a path like "/workers/3/pause" (given
+// prefix "/workers/") into ("3", "pause", true).
+func splitTrailingAction(path, prefix string) (id, action string, ok bool) {
+	rest := strings.TrimPrefix(path, prefix)
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warn("Failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,151 @@
+package batchproxyplay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	cfbatch "dayusch/internal/pkg/api/cfbatch/v2"
+	"dayusch/internal/pkg/api/yarun"
+	"dayusch/internal/pkg/app/batchproxyplay/control"
+)
+
+// clientSet holds the current parentApi/yarunClient pair behind a
+// mutex, so ReloadConfig can swap in freshly-constructed clients
+// without disturbing workers already running against a cloned copy
+// of the old pair.
+type clientSet struct {
+	mu            sync.RWMutex
+	parentApi     *cfbatch.CFBatchApi
+	yarunClient   *yarun.YarunApi
+	proxySelector yarun.ProxySelector
+}
+
+func newClientSet(parentApi *cfbatch.CFBatchApi, yarunClient *yarun.YarunApi) *clientSet {
+	return &clientSet{parentApi: parentApi, yarunClient: yarunClient}
+}
+
+func (c *clientSet) get() (*cfbatch.CFBatchApi, *yarun.YarunApi) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.parentApi, c.yarunClient
+}
+
+// attachProxySelector registers the ProxySelector that acquires proxies
+// through this clientSet's yarun client, so every subsequent set call
+// repoints it at the new client instead of leaving it on whichever one
+// it was first built from.
+func (c *clientSet) attachProxySelector(s yarun.ProxySelector) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.proxySelector = s
+}
+
+func (c *clientSet) set(parentApi *cfbatch.CFBatchApi, yarunClient *yarun.YarunApi) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.parentApi = parentApi
+	c.yarunClient = yarunClient
+	if c.proxySelector != nil {
+		c.proxySelector.SetClient(yarunClient)
+	}
+}
+
+// runtimeController implements control.Controller over a live Run
+// loop: the set of workers currently in flight, the swappable client
+// pair they're cloned from, and the drain flag Run's main loop polls.
+type runtimeController struct {
+	clients *clientSet
+
+	mu      sync.Mutex
+	workers map[int]*worker
+
+	draining *int32
+}
+
+func newRuntimeController(clients *clientSet, draining *int32) *runtimeController {
+	return &runtimeController{
+		clients:  clients,
+		workers:  make(map[int]*worker),
+		draining: draining,
+	}
+}
+
+func (c *runtimeController) register(w *worker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.workers[w.id] = w
+}
+
+func (c *runtimeController) unregister(w *worker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.workers, w.id)
+}
+
+func (c *runtimeController) ListWorkers() []control.WorkerSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshots := make([]control.WorkerSnapshot, 0, len(c.workers))
+	for _, w := range c.workers {
+		snapshots = append(snapshots, w.Snapshot())
+	}
+	return snapshots
+}
+
+func (c *runtimeController) PauseWorker(id int) error {
+	c.mu.Lock()
+	w, ok := c.workers[id]
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no worker with id %d", id)
+	}
+	w.Pause()
+	return nil
+}
+
+func (c *runtimeController) ResumeWorker(id int) error {
+	c.mu.Lock()
+	w, ok := c.workers[id]
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no worker with id %d", id)
+	}
+	w.Resume()
+	return nil
+}
+
+func (c *runtimeController) BlockProxy(proxyID string) error {
+	_, yarunClient := c.clients.get()
+	_, err := yarunClient.BlockProxy(context.Background(), proxyID)
+	return err
+}
+
+func (c *runtimeController) UnblockProxy(proxyID string) error {
+	_, yarunClient := c.clients.get()
+	_, err := yarunClient.UnblockProxy(context.Background(), proxyID, "", false)
+	return err
+}
+
+func (c *runtimeController) ReloadConfig(baseURL, token, yarunBaseURL, yarunToken string) error {
+	if baseURL == "" || token == "" || yarunBaseURL == "" || yarunToken == "" {
+		return fmt.Errorf("base_url, token, yarun_base_url, and yarun_token are all required")
+	}
+	oldParentApi, oldYarunClient := c.clients.get()
+	c.clients.set(cfbatch.NewCFBatchApi(baseURL, token), yarun.NewYarunApi(yarunBaseURL, yarunToken))
+	// Workers already cloned from the old pair keep using it until they
+	// finish their current round, but neither old client's auth backend
+	// is needed once nothing will clone it again: clients.set just
+	// repointed proxySelector (see main.go) at the new yarun client, so
+	// the old one is no longer aliased anywhere but those in-flight
+	// workers' own copies.
+	oldParentApi.Stop()
+	oldYarunClient.Stop()
+	return nil
+}
+
+func (c *runtimeController) Drain() {
+	atomic.StoreInt32(c.draining, 1)
+}
@@ -0,0 +1,70 @@
+package batchproxyplay
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// eventLogEntry is one line of an eventSink's output: the same facts a
+// per-spin log.Info call already carries, shaped so a run can be
+// replayed/analysed offline instead of grepping interleaved goroutine
+// logs after the fact (e.g. reconstructing what led to a jackpot).
+type eventLogEntry struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Message   string         `json:"message"`
+	WorkerID  int            `json:"worker_id,omitempty"`
+	ProxyID   string         `json:"proxy_id,omitempty"`
+	Fields    map[string]any `json:"fields,omitempty"`
+}
+
+// eventSink appends eventLogEntry lines to a file as newline-delimited
+// JSON. A nil *eventSink is valid and log is then a no-op, so callers
+// don't need to branch on whether Config.EventLogFile was set.
+type eventSink struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// newEventSink opens path for appending. It returns (nil, nil) if path
+// is empty, so the sink is simply disabled.
+func newEventSink(path string) (*eventSink, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventSink{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (s *eventSink) log(workerID int, proxyID, message string, fields map[string]any) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enc.Encode(eventLogEntry{
+		Timestamp: time.Now(),
+		Message:   message,
+		WorkerID:  workerID,
+		ProxyID:   proxyID,
+		Fields:    fields,
+	})
+}
+
+// Close flushes and closes the underlying file. It's safe to call on a
+// nil *eventSink.
+func (s *eventSink) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.file.Close()
+}
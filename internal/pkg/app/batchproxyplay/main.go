@@ -2,7 +2,7 @@ package batchproxyplay
 
 import (
 	"context"
-	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
@@ -13,36 +13,98 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/goccy/go-yaml"
 	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
 
 	cfbatch "dayusch/internal/pkg/api/cfbatch/v2"
-	"dayusch/internal/pkg/api/pragmatic"
 	"dayusch/internal/pkg/api/yarun"
-	"dayusch/internal/pkg/helper"
+	"dayusch/internal/pkg/app/batch"
+	"dayusch/internal/pkg/app/batchproxyplay/control"
 )
 
 type Config struct {
-	BaseURL       string `yaml:"base_url"`
-	Token         string `yaml:"token"`
-	ProxyUsername string `yaml:"proxy_username"`
-	ProxyPassword string `yaml:"proxy_password"`
-	YarunBaseURL  string `yaml:"yarun_base_url"`
-	YarunToken    string `yaml:"yarun_token"`
+	BaseURL         string `yaml:"base_url"`
+	Token           string `yaml:"token"`
+	ProxyUsername   string `yaml:"proxy_username"`
+	ProxyPassword   string `yaml:"proxy_password"`
+	YarunBaseURL    string `yaml:"yarun_base_url"`
+	YarunToken      string `yaml:"yarun_token"`
+	WireGuardConfig string `yaml:"wireguard_config"`
+
+	// SpinsPerSecond paces each worker's DoSpin/DoCollect calls. Defaults to
+	// ~31, matching the fixed 32ms sleep this replaces.
+	SpinsPerSecond float64 `yaml:"spins_per_second"`
+	// ProxyMinSamples is how many rounds a proxy needs before its success
+	// rate EWMA is trusted enough to block on.
+	ProxyMinSamples int `yaml:"proxy_min_samples"`
+	// ProxyBlockThreshold is the EWMA success rate (in [0,1]) below which a
+	// proxy with ProxyMinSamples rounds gets blocked.
+	ProxyBlockThreshold float64 `yaml:"proxy_block_threshold"`
+	// YarunRequestsPerSecond paces the outer round loop's proxy
+	// acquisitions, so fast-failing rounds can't hammer yarun.
+	YarunRequestsPerSecond float64 `yaml:"yarun_requests_per_second"`
+
+	// ControlAddr, if set, serves the control plane (worker list,
+	// pause/resume, proxy block/unblock, config reload, event stream)
+	// on that address. Leave empty to disable it.
+	ControlAddr string `yaml:"control_addr"`
+
+	// MetricsAddr, if set, serves Prometheus metrics on that address at
+	// /metrics. Leave empty to disable it.
+	MetricsAddr string `yaml:"metrics_addr"`
+	// EventLogFile, if set, appends a structured JSON line for every
+	// per-spin/collect/block/jackpot event to that file, so a run can
+	// be replayed/analysed offline. Leave empty to disable it.
+	EventLogFile string `yaml:"event_log_file"`
+
+	// ProxySelector picks which yarun.ProxySelector policy the round
+	// loop acquires proxies through: "round_robin" (default),
+	// "ewma_latency", or "power_of_two".
+	ProxySelector string `yaml:"proxy_selector"`
 }
 
-func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
+// newProxySelector builds the yarun.ProxySelector named by kind,
+// defaulting to round-robin for an empty or unrecognized kind.
+func newProxySelector(kind string, api *yarun.YarunApi, limit int) yarun.ProxySelector {
+	switch kind {
+	case "ewma_latency":
+		return yarun.NewEWMALatencySelector(api, limit)
+	case "power_of_two":
+		return yarun.NewPowerOfTwoSelector(api, limit)
+	default:
+		return yarun.NewRoundRobinSelector(api, limit)
+	}
+}
+
+const (
+	defaultSpinsPerSecond         = 1000.0 / 32.0 // matches the fixed 32ms sleep this replaces
+	defaultProxyMinSamples        = 3
+	defaultProxyBlockThreshold    = 0.5
+	defaultYarunRequestsPerSecond = 2.0
+)
+
+func Run(maxConcurrent, batchLimit, delay uint, inputFile, wireguardConfigFile string) {
 	var config Config
 
 	// Create a root context that will be cancelled on shutdown
 	rootCtx, rootCancel := context.WithCancel(context.Background())
 	defer rootCancel()
 
-	// Set up signal handling for graceful shutdown
+	// draining is set by the first shutdown signal (or a control-plane
+	// Drain call): the main loop stops starting new rounds but lets
+	// in-flight workers finish instead of aborting them. A second
+	// signal forces a hard rootCtx cancel for when draining hangs.
+	var draining int32
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigChan
-		log.Info("Received shutdown signal, stopping gracefully...")
-		rootCancel() // This will cancel all derived contexts
+		log.Info("Received shutdown signal, draining in-flight workers...")
+		atomic.StoreInt32(&draining, 1)
+
+		<-sigChan
+		log.Info("Received second shutdown signal, cancelling immediately...")
+		rootCancel()
 	}()
 
 	if inputFile != "" {
@@ -65,14 +127,36 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 		config.ProxyPassword = os.Getenv("PROXY_PASSWORD")
 		config.YarunBaseURL = os.Getenv("YARUN_BASE_URL")
 		config.YarunToken = os.Getenv("YARUN_TOKEN")
+		config.WireGuardConfig = os.Getenv("WIREGUARD_CONFIG")
+		config.ControlAddr = os.Getenv("CONTROL_ADDR")
+		config.MetricsAddr = os.Getenv("METRICS_ADDR")
+		config.EventLogFile = os.Getenv("EVENT_LOG_FILE")
+		config.ProxySelector = os.Getenv("PROXY_SELECTOR")
 
 		log.Info("Loaded config from environment variables")
 	}
 
+	if wireguardConfigFile != "" {
+		config.WireGuardConfig = wireguardConfigFile
+	}
+
 	if config.BaseURL == "" || config.Token == "" || config.ProxyUsername == "" || config.ProxyPassword == "" || config.YarunBaseURL == "" || config.YarunToken == "" {
 		log.Fatal("Missing required configuration: base_url, token, proxy_username, proxy_password, yarun_base_url, yarun_token")
 	}
 
+	if config.SpinsPerSecond <= 0 {
+		config.SpinsPerSecond = defaultSpinsPerSecond
+	}
+	if config.ProxyMinSamples <= 0 {
+		config.ProxyMinSamples = defaultProxyMinSamples
+	}
+	if config.ProxyBlockThreshold <= 0 {
+		config.ProxyBlockThreshold = defaultProxyBlockThreshold
+	}
+	if config.YarunRequestsPerSecond <= 0 {
+		config.YarunRequestsPerSecond = defaultYarunRequestsPerSecond
+	}
+
 	log.Info("Starting batchproxy",
 		"baseURL", config.BaseURL,
 		"yarunBaseURL", config.YarunBaseURL,
@@ -81,36 +165,148 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 		"delay", delay,
 	)
 
-	// Create parent CFBatchApi
-	parentApi := cfbatch.NewCFBatchApi(config.BaseURL, config.Token)
-
-	// Create yarun API client
-	yarunClient := yarun.NewYarunApi(config.YarunBaseURL, config.YarunToken)
+	// Create parent CFBatchApi and yarun API client, held behind a
+	// clientSet so the control plane's ReloadConfig can swap in a fresh
+	// pair without disturbing workers already cloned from the old one.
+	clients := newClientSet(
+		cfbatch.NewCFBatchApi(config.BaseURL, config.Token),
+		yarun.NewYarunApi(config.YarunBaseURL, config.YarunToken),
+	)
+	// Deferred rather than a plain Stop() call on the clients above,
+	// since ReloadConfig can swap clients.get()'s pair out from under us
+	// before Run returns.
+	defer func() {
+		parentApi, yarunClient := clients.get()
+		parentApi.Stop()
+		yarunClient.Stop()
+	}()
 
 	log.Info("Created parent CFBatchApi and yarun client instances")
 
+	events := control.NewEventBus()
+	controller := newRuntimeController(clients, &draining)
+
+	// proxyTracker replaces the old "block on this round's failure rate
+	// alone" logic with an EWMA of success rate per proxy, persisted across
+	// rounds. yarunLimiter paces proxy acquisition so a string of
+	// fast-failing rounds can't hammer yarun.
+	proxyTracker := newProxyStatsTracker(config.ProxyMinSamples, config.ProxyBlockThreshold)
+	yarunLimiter := rate.NewLimiter(rate.Limit(config.YarunRequestsPerSecond), 1)
+
+	// proxySelector replaces the old "grab maxConcurrent proxies from
+	// GetProxies and use them blindly" approach: it caches a pool of
+	// proxies behind whichever selection policy config.ProxySelector
+	// names, and its per-acquire Release feeds success/latency back
+	// into that policy's own health tracking and block/unblock calls.
+	// It's built once from the initial yarun client, like proxyTracker,
+	// but attachProxySelector below repoints it at whatever client
+	// clients.set swaps in next, so a ReloadConfig takes effect for
+	// proxy acquisition too, not just the per-round parentApi/yarunClient
+	// workers are cloned from. This has to happen before the control
+	// plane (below) starts listening: ReloadConfig runs through
+	// clients.set, and clients.set only repoints a selector that's
+	// already attached, so a reload landing before attachProxySelector
+	// would otherwise stop the very client proxySelector is about to be
+	// built around.
+	_, initialYarunClient := clients.get()
+	proxySelector := newProxySelector(config.ProxySelector, initialYarunClient, int(maxConcurrent))
+	clients.attachProxySelector(proxySelector)
+
+	runMetrics := newMetrics()
+	if config.MetricsAddr != "" {
+		log.Info("Starting metrics server", "addr", config.MetricsAddr)
+		runMetrics.serve(rootCtx, config.MetricsAddr)
+	}
+
+	eventLog, err := newEventSink(config.EventLogFile)
+	if err != nil {
+		log.Fatal("Failed to open event log file", "file", config.EventLogFile, "error", err)
+	}
+	defer eventLog.Close()
+
+	if config.ControlAddr != "" {
+		controlServer := control.NewServer(controller, events)
+		go func() {
+			log.Info("Starting control plane", "addr", config.ControlAddr)
+			if err := controlServer.ListenAndServe(config.ControlAddr); err != nil && err != http.ErrServerClosed {
+				log.Error("Control plane stopped", "error", err)
+			}
+		}()
+		go func() {
+			<-rootCtx.Done()
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer shutdownCancel()
+			controlServer.Shutdown(shutdownCtx)
+		}()
+	}
+
+	// Bring up the WireGuard egress tunnel, if configured. It's shared by
+	// every worker for the life of the process (workers tunnel over it and
+	// still HTTP-proxy through dataimpulse via SetProxyURL below, so the two
+	// egress modes compose instead of being mutually exclusive), and torn
+	// down once when rootCtx is cancelled rather than per round.
+	var wgDialer *batch.WireDialer
+	if config.WireGuardConfig != "" {
+		var err error
+		wgDialer, err = batch.NewDialerFromConfigFile(config.WireGuardConfig)
+		if err != nil {
+			log.Fatal("Failed to bring up WireGuard tunnel", "config", config.WireGuardConfig, "error", err)
+		}
+		log.Info("WireGuard tunnel up", "config", config.WireGuardConfig)
+
+		go func() {
+			<-rootCtx.Done()
+			wgDialer.Device.Close()
+		}()
+	}
+
 	for {
-		// Check for shutdown signal
+		// Check for shutdown signal or a drain request (from a signal or
+		// the control plane): either way, stop starting new rounds.
 		select {
 		case <-rootCtx.Done():
 			log.Info("Shutdown requested, stopping main loop")
 			return
 		default:
 		}
+		if atomic.LoadInt32(&draining) != 0 {
+			log.Info("Drain requested, stopping main loop")
+			return
+		}
 
 		log.Info("Starting new batch round")
 
-		// Get available proxies from yarun
+		if err := yarunLimiter.Wait(rootCtx); err != nil {
+			log.Info("Shutdown requested while rate-limiting GetProxies")
+			return
+		}
+
+		parentApi, yarunClient := clients.get()
+
+		// Acquire up to maxConcurrent proxies through proxySelector
+		// instead of a single blind GetProxies(limit) call, so the
+		// selection policy decides which proxies this round gets.
 		ctx, cancel := context.WithTimeout(rootCtx, 30*time.Second)
-		proxiesResp, err := yarunClient.GetProxies(ctx, int(maxConcurrent))
+		proxies := make([]yarun.ProxyResponse, 0, maxConcurrent)
+		releases := make([]func(bool, time.Duration), 0, maxConcurrent)
+		var acquireErr error
+		for i := 0; i < int(maxConcurrent); i++ {
+			proxy, release, err := proxySelector.Acquire(ctx)
+			if err != nil {
+				acquireErr = err
+				break
+			}
+			proxies = append(proxies, *proxy)
+			releases = append(releases, release)
+		}
 		cancel()
 
-		if err != nil {
+		if len(proxies) == 0 {
 			if ctx.Err() == context.Canceled {
 				log.Info("Proxy request cancelled due to shutdown")
 				return
 			}
-			log.Error("Failed to get proxies from yarun", "error", err)
+			log.Error("Failed to acquire proxies from yarun", "error", acquireErr)
 
 			// Check for shutdown before sleeping
 			select {
@@ -122,273 +318,34 @@ func Run(maxConcurrent, batchLimit, delay uint, inputFile string) {
 			continue
 		}
 
-		if len(proxiesResp.Proxies) == 0 {
-			log.Warn("No available proxies returned from yarun")
-
-			// Check for shutdown before sleeping
-			select {
-			case <-rootCtx.Done():
-				log.Info("Shutdown requested during delay")
-				return
-			case <-time.After(time.Duration(delay) * time.Second):
-			}
-			continue
-		}
-
-		log.Info("Got proxies from yarun", "count", len(proxiesResp.Proxies))
+		log.Info("Acquired proxies from yarun", "count", len(proxies))
 
 		// Create semaphore for controlling concurrency
 		sem := semaphore.NewWeighted(int64(maxConcurrent))
 		var wg sync.WaitGroup
 
-		// Create concurrent workers using available proxies
-		for i, proxy := range proxiesResp.Proxies {
-			if i >= int(maxConcurrent) {
-				break // Don't exceed maxConcurrent
-			}
+		// Create concurrent workers using acquired proxies
+		for i, proxy := range proxies {
+			w := newWorker(i, proxy, rootCtx, config, batchLimit, parentApi, yarunClient, wgDialer, proxyTracker, releases[i], events, runMetrics, eventLog)
+			controller.register(w)
 
 			wg.Add(1)
-			go func(workerID int, proxy yarun.ProxyResponse) {
+			go func(w *worker) {
 				defer wg.Done()
+				defer controller.unregister(w)
+
+				runMetrics.activeWorkers.Inc()
+				defer runMetrics.activeWorkers.Dec()
 
 				// Acquire semaphore
 				if err := sem.Acquire(context.Background(), 1); err != nil {
-					log.Error("Failed to acquire semaphore", "workerID", workerID, "error", err)
+					log.Error("Failed to acquire semaphore", "workerID", w.id, "error", err)
 					return
 				}
 				defer sem.Release(1)
 
-				log.Info("Worker started", "workerID", workerID, "assignedPort", proxy.Port)
-
-				// Send batch request with root context
-				ctx, cancel := context.WithTimeout(rootCtx, 30*time.Second)
-				defer cancel()
-
-				api := parentApi.Clone()
-
-				// Set user agent first (round-robin)
-				userAgent := helper.GetNextUserAgent()
-				api.SetUserAgent(userAgent)
-
-				// Then set proxy URL
-				proxyURL := fmt.Sprintf("http://%s:%s@gw.dataimpulse.com:%d", config.ProxyUsername, config.ProxyPassword, proxy.Port)
-				api.SetProxyURL(proxyURL)
-
-				responses, err := api.GetBatchLink(ctx, int(batchLimit))
-				shouldBlockProxy := false
-
-				if err != nil {
-					if ctx.Err() == context.Canceled {
-						log.Info("Batch request cancelled due to shutdown", "workerID", workerID)
-						return
-					}
-					log.Error("SendBatch failed", "workerID", workerID, "port", proxy.Port, "error", err)
-					shouldBlockProxy = true
-				} else {
-					log.Info("SendBatch completed successfully",
-						"workerID", workerID,
-						"limit", batchLimit,
-						"responseCount", len(responses))
-
-					// Analyze response status to determine if proxy should be blocked
-					var failedCount int32
-					totalCount := len(responses)
-
-					// Process each response concurrently using goroutines
-					var responseWg sync.WaitGroup
-					responseSem := semaphore.NewWeighted(int64(len(responses))) // Allow all responses to run concurrently
-
-					for _, response := range responses {
-						responseWg.Add(1)
-						go func(resp cfbatch.BatchResponseLink) {
-							defer responseWg.Done()
-
-							// Check for shutdown signal
-							select {
-							case <-rootCtx.Done():
-								return
-							default:
-							}
-
-							// Acquire semaphore for this response processing
-							if err := responseSem.Acquire(rootCtx, 1); err != nil {
-								if err == context.Canceled {
-									log.Info("Response processing cancelled due to shutdown")
-									return
-								}
-								log.Error("Failed to acquire response semaphore", "error", err)
-								return
-							}
-							defer responseSem.Release(1)
-
-							if !resp.Status {
-								atomic.AddInt32(&failedCount, 1)
-							}
-
-							if resp.Link != nil {
-								// state
-								var index *int
-								var counter *int
-
-								pp := pragmatic.NewPragmaticPlay(rootCtx, *resp.Link, userAgent)
-
-								sessionData, err := pp.LoadSession()
-								if err != nil {
-									log.Error("error on load game", "error", err)
-									return
-								}
-
-								if sessionData == nil {
-									log.Error("error on load game: sessionData is nil")
-									return
-								}
-
-								initResData, err := pp.DoInit(sessionData.MGCKey, resp.GameSymbol)
-								if err != nil {
-									log.Error("error on init game", "error", err)
-									return
-								}
-
-								tmpIndex := initResData.Index + 1
-								index = &tmpIndex
-								tmpCounter := initResData.Counter + 1
-								counter = &tmpCounter
-
-								log.Info("user info", "balance", initResData.Balance, "total_win", initResData.TotalWin, "next_action", initResData.NextAction)
-
-								// loop for spin
-								for {
-									// Check for shutdown signal in the game loop
-									select {
-									case <-rootCtx.Done():
-										log.Info("Shutdown requested, stopping game loop")
-										return
-									default:
-									}
-
-									// Check balance threshold
-									if initResData.NextAction == "s" && (initResData.Balance <= 500.0 || initResData.Balance >= 100_000.0) {
-										log.Info("threshold reached, stopping loop", "balance", initResData.Balance)
-
-										if initResData.Balance >= 100_000.0 {
-											log.Info("JACKPOT", "balance", initResData.Balance)
-										}
-
-										// tmx.yarunApi.UpdateUserBalance(user.ID, initResData.Balance, homeData.Data.AmountInfo.UsableCurrency)
-										updateCtx, updateCancel := context.WithTimeout(rootCtx, 30*time.Second)
-										_, err := yarunClient.UpdateUserBalance(updateCtx, resp.ID, initResData.Balance, resp.Coin)
-										updateCancel()
-										if err != nil {
-											if updateCtx.Err() == context.Canceled {
-												log.Info("Update balance cancelled due to shutdown")
-												return
-											}
-											log.Error("error on update user balance", "error", err)
-											return
-										}
-
-										break
-									}
-
-									// Progressive coin logic
-									balance := initResData.Balance
-									amount := 400.0
-									if balance > 10000 && balance <= 30000 {
-										amount = 600.0
-									} else if balance > 30000 && balance <= 50000 {
-										amount = 800.0
-									} else if balance > 50000 && balance <= 100000 {
-										amount = 1000.0
-									}
-
-									coinValue := int(amount / 20.0)
-									coin := &coinValue
-
-									if initResData.NextAction == "s" {
-										respData, err := pp.DoSpin(sessionData.MGCKey, resp.GameSymbol, *coin, *index, *counter, "aq")
-										if err != nil {
-											log.Error("error on spin game", "error", err)
-											return
-										}
-
-										tmpIndex = respData.Index + 1
-										index = &tmpIndex
-										tmpCounter = respData.Counter + 1
-										counter = &tmpCounter
-
-										log.Info("spin action info", "balance", respData.Balance, "total_win", respData.TotalWin, "new_index", *index, "new_counter", *counter, "next_action", respData.NextAction, "coin", *coin, "amount", amount)
-										initResData = respData // update state for next action
-									}
-
-									if initResData.NextAction == "c" {
-										respData, err := pp.DoCollect(sessionData.MGCKey, resp.GameSymbol, *index, *counter)
-										if err != nil {
-											log.Error("error on collect", "error", err)
-											return
-										}
-
-										tmpIndex = respData.Index + 1
-										index = &tmpIndex
-										tmpCounter = respData.Counter + 1
-										counter = &tmpCounter
-
-										log.Info("collect action info", "balance", respData.Balance, "total_win", respData.TotalWin, "new_index", *index, "new_counter", *counter, "next_action", respData.NextAction)
-										initResData = respData // update state for next action
-									}
-
-									time.Sleep(32 * time.Millisecond)
-								}
-							}
-						}(response)
-					}
-
-					// Wait for all response processing to complete
-					log.Info("Waiting for all response processing to complete", "totalResponses", totalCount)
-					responseWg.Wait()
-					log.Info("All response processing completed")
-
-					// Check if failure rate is >= 50%
-					if totalCount > 0 {
-						finalFailedCount := atomic.LoadInt32(&failedCount)
-						failureRate := float64(finalFailedCount) / float64(totalCount)
-						log.Info("Batch response analysis",
-							"workerID", workerID,
-							"totalResponses", totalCount,
-							"failedResponses", finalFailedCount,
-							"failureRate", fmt.Sprintf("%.2f%%", failureRate*100))
-
-						if failureRate >= 0.5 {
-							shouldBlockProxy = true
-							log.Warn("High failure rate detected, will block proxy",
-								"workerID", workerID,
-								"port", proxy.Port,
-								"failureRate", fmt.Sprintf("%.2f%%", failureRate*100))
-						}
-					}
-				}
-
-				// Block proxy if needed (either due to API error or high failure rate)
-				if shouldBlockProxy {
-					blockCtx, blockCancel := context.WithTimeout(rootCtx, 30*time.Second)
-					_, blockErr := yarunClient.BlockProxy(blockCtx, proxy.ID)
-					blockCancel()
-
-					if blockErr != nil {
-						if blockCtx.Err() == context.Canceled {
-							log.Info("Block proxy cancelled due to shutdown", "workerID", workerID)
-							return
-						}
-						log.Error("Failed to block proxy", "workerID", workerID, "port", proxy.Port, "error", blockErr)
-					} else {
-						log.Info("Proxy blocked", "workerID", workerID, "port", proxy.Port, "reason", func() string {
-							if err != nil {
-								return "API error"
-							}
-							return "high failure rate (>=50%)"
-						}())
-					}
-				}
-			}(i, proxy)
+				w.run()
+			}(w)
 		}
 
 		// Wait for all workers to complete
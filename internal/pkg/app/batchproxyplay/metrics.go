@@ -0,0 +1,95 @@
+package batchproxyplay
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics holds this run's Prometheus collectors on a private
+// registry, so Run can be invoked more than once without colliding
+// with the global default registry.
+type metrics struct {
+	registry *prometheus.Registry
+
+	spinsTotal       *prometheus.CounterVec
+	collectsTotal    *prometheus.CounterVec
+	proxyBlocksTotal *prometheus.CounterVec
+	jackpotsTotal    *prometheus.CounterVec
+
+	spinDuration         prometheus.Histogram
+	getBatchLinkDuration prometheus.Histogram
+
+	activeWorkers   prometheus.Gauge
+	ewmaSuccessRate *prometheus.GaugeVec
+}
+
+func newMetrics() *metrics {
+	registry := prometheus.NewRegistry()
+	factory := promauto.With(registry)
+
+	return &metrics{
+		registry: registry,
+
+		spinsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "batchproxy_spins_total",
+			Help: "Spins attempted, labelled by game and result.",
+		}, []string{"game", "result"}),
+		collectsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "batchproxy_collects_total",
+			Help: "Collects attempted, labelled by game.",
+		}, []string{"game"}),
+		proxyBlocksTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "batchproxy_proxy_blocks_total",
+			Help: "Proxies blocked, labelled by reason.",
+		}, []string{"reason"}),
+		jackpotsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "batchproxy_jackpots_total",
+			Help: "Jackpots hit, labelled by game.",
+		}, []string{"game"}),
+
+		spinDuration: factory.NewHistogram(prometheus.HistogramOpts{
+			Name: "batchproxy_spin_duration_seconds",
+			Help: "Latency of DoSpin calls.",
+		}),
+		getBatchLinkDuration: factory.NewHistogram(prometheus.HistogramOpts{
+			Name: "batchproxy_getbatchlink_duration_seconds",
+			Help: "Latency of GetBatchLink calls.",
+		}),
+
+		activeWorkers: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "batchproxy_active_workers",
+			Help: "Workers currently in flight.",
+		}),
+		ewmaSuccessRate: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "batchproxy_ewma_success_rate",
+			Help: "Per-proxy EWMA success rate tracked by proxyStatsTracker.",
+		}, []string{"proxy_id"}),
+	}
+}
+
+// serve starts a metrics HTTP server on addr, shutting it down once
+// ctx is done.
+func (m *metrics) serve(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("Metrics server stopped", "error", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+}
@@ -0,0 +1,66 @@
+package batchproxyplay
+
+import "sync"
+
+// proxyStatsEWMAAlpha weights how quickly a proxy's tracked success rate
+// reacts to a new round, versus its history.
+const proxyStatsEWMAAlpha = 0.3
+
+// proxyStats is one proxy's running health: an EWMA of its success rate
+// across rounds and how many rounds have contributed to it.
+type proxyStats struct {
+	successRate float64 // EWMA, in [0,1]
+	samples     int
+}
+
+// proxyStatsTracker keeps a per-proxy EWMA of success rate across rounds,
+// so a block decision reflects a proxy's track record instead of treating
+// one bad batch as representative.
+type proxyStatsTracker struct {
+	mu             sync.Mutex
+	stats          map[string]*proxyStats
+	minSamples     int
+	blockThreshold float64
+}
+
+func newProxyStatsTracker(minSamples int, blockThreshold float64) *proxyStatsTracker {
+	return &proxyStatsTracker{
+		stats:          make(map[string]*proxyStats),
+		minSamples:     minSamples,
+		blockThreshold: blockThreshold,
+	}
+}
+
+// record folds one round's success rate for proxyID into its EWMA and
+// reports whether the proxy should now be blocked: its EWMA has dropped
+// below blockThreshold and it has accumulated at least minSamples rounds,
+// so a single bad batch can't evict an otherwise-healthy proxy.
+func (t *proxyStatsTracker) record(proxyID string, successRate float64) (shouldBlock bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.stats[proxyID]
+	if !ok {
+		s = &proxyStats{successRate: successRate}
+		t.stats[proxyID] = s
+	} else {
+		s.successRate = proxyStatsEWMAAlpha*successRate + (1-proxyStatsEWMAAlpha)*s.successRate
+	}
+	s.samples++
+
+	return s.samples >= t.minSamples && s.successRate < t.blockThreshold
+}
+
+// successRateOf returns proxyID's current EWMA success rate, for
+// exporting as the batchproxy_ewma_success_rate gauge. ok is false if
+// no round has been recorded for proxyID yet.
+func (t *proxyStatsTracker) successRateOf(proxyID string) (rate float64, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.stats[proxyID]
+	if !ok {
+		return 0, false
+	}
+	return s.successRate, true
+}
@@ -0,0 +1,449 @@
+package batchproxyplay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+
+	cfbatch "dayusch/internal/pkg/api/cfbatch/v2"
+	"dayusch/internal/pkg/api/pragmatic"
+	"dayusch/internal/pkg/api/yarun"
+	"dayusch/internal/pkg/app/batch"
+	"dayusch/internal/pkg/app/batchproxyplay/control"
+	"dayusch/internal/pkg/helper"
+)
+
+// worker runs one proxy's batch-and-play round, and lives for the
+// whole of that round so the control plane can pause, resume, or
+// snapshot it by id. It's built fresh by Run for every round.
+type worker struct {
+	id          int
+	proxy       yarun.ProxyResponse
+	rootCtx     context.Context
+	config      Config
+	batchLimit  uint
+	parentApi   *cfbatch.CFBatchApi
+	yarunClient *yarun.YarunApi
+	wgDialer    *batch.WireDialer
+	tracker     *proxyStatsTracker
+	release     func(success bool, latency time.Duration)
+	events      *control.EventBus
+	metrics     *metrics
+	eventLog    *eventSink
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	paused    bool
+	status    string
+	round     int
+	startedAt time.Time
+}
+
+func newWorker(id int, proxy yarun.ProxyResponse, rootCtx context.Context, config Config, batchLimit uint, parentApi *cfbatch.CFBatchApi, yarunClient *yarun.YarunApi, wgDialer *batch.WireDialer, tracker *proxyStatsTracker, release func(success bool, latency time.Duration), events *control.EventBus, m *metrics, eventLog *eventSink) *worker {
+	w := &worker{
+		id:          id,
+		proxy:       proxy,
+		rootCtx:     rootCtx,
+		config:      config,
+		batchLimit:  batchLimit,
+		parentApi:   parentApi,
+		yarunClient: yarunClient,
+		wgDialer:    wgDialer,
+		tracker:     tracker,
+		release:     release,
+		events:      events,
+		metrics:     m,
+		eventLog:    eventLog,
+		status:      "running",
+		startedAt:   time.Now(),
+	}
+	w.cond = sync.NewCond(&w.mu)
+
+	go func() {
+		<-rootCtx.Done()
+		w.mu.Lock()
+		w.cond.Broadcast()
+		w.mu.Unlock()
+	}()
+
+	return w
+}
+
+// Snapshot returns a point-in-time view of this worker for the control
+// plane's ListWorkers.
+func (w *worker) Snapshot() control.WorkerSnapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return control.WorkerSnapshot{
+		ID:        w.id,
+		ProxyID:   w.proxy.ID,
+		ProxyPort: w.proxy.Port,
+		Round:     w.round,
+		Status:    w.status,
+		StartedAt: w.startedAt,
+	}
+}
+
+// Pause stops this worker's spin loop after its current spin, without
+// tearing down its session.
+func (w *worker) Pause() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.paused = true
+	w.status = "paused"
+}
+
+// Resume lets a paused worker continue spinning.
+func (w *worker) Resume() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.paused = false
+	w.status = "running"
+	w.cond.Broadcast()
+}
+
+// waitIfPaused blocks the caller while the worker is paused, returning
+// early if rootCtx is cancelled.
+func (w *worker) waitIfPaused() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.paused {
+		select {
+		case <-w.rootCtx.Done():
+			return
+		default:
+		}
+		w.cond.Wait()
+	}
+}
+
+func (w *worker) publish(eventType, message string) {
+	if w.events == nil {
+		return
+	}
+	w.events.Publish(control.Event{
+		Type:      eventType,
+		WorkerID:  w.id,
+		ProxyID:   w.proxy.ID,
+		Message:   message,
+		Timestamp: time.Now(),
+	})
+}
+
+// run sends one batch request through this worker's proxy and plays
+// every resulting game link to completion. It's the same logic the
+// per-proxy goroutine in Run used to inline, pulled out so the control
+// plane has something addressable to pause/resume/snapshot.
+func (w *worker) run() {
+	log.Info("Worker started", "workerID", w.id, "assignedPort", w.proxy.Port)
+	w.publish("worker_started", fmt.Sprintf("assigned port %d", w.proxy.Port))
+
+	// spinLimiter paces this worker's DoSpin/DoCollect calls, shared by
+	// every response goroutine it spawns below.
+	spinLimiter := rate.NewLimiter(rate.Limit(w.config.SpinsPerSecond), 1)
+
+	ctx, cancel := context.WithTimeout(w.rootCtx, 30*time.Second)
+	defer cancel()
+
+	api := w.parentApi.Clone()
+
+	// If a WireGuard tunnel is configured, dial over it; the proxy URL
+	// set below still applies on top, so traffic tunnels over
+	// WireGuard first and then HTTP-proxies through dataimpulse.
+	if w.wgDialer != nil {
+		api.SetDialContext(w.wgDialer.DialContext)
+	}
+
+	userAgent := helper.GetNextUserAgent()
+	api.SetUserAgent(userAgent)
+
+	proxyURL := fmt.Sprintf("http://%s:%s@gw.dataimpulse.com:%d", w.config.ProxyUsername, w.config.ProxyPassword, w.proxy.Port)
+	api.SetProxyURL(proxyURL)
+
+	batchStart := time.Now()
+	responses, err := api.GetBatchLink(ctx, int(w.batchLimit))
+	if w.metrics != nil {
+		w.metrics.getBatchLinkDuration.Observe(time.Since(batchStart).Seconds())
+	}
+	shouldBlockProxy := false
+
+	// roundSucceeded is this round's own success/failure, independent of
+	// shouldBlockProxy: shouldBlockProxy only flips true on a SendBatch
+	// error or once proxyTracker's slower-moving, multi-round EWMA
+	// crosses its block threshold, so a round that's mostly failures but
+	// hasn't tripped that EWMA yet would otherwise still report success
+	// to the proxySelector below.
+	roundSucceeded := true
+
+	// Report this round's outcome back to whichever proxySelector
+	// handed out w.proxy, regardless of how run returns below: that's
+	// what returns the proxy to the selector's cached pool and feeds
+	// its health tracking, on top of the explicit tracker-driven
+	// BlockProxy call further down.
+	defer func() {
+		if w.release != nil {
+			w.release(roundSucceeded, time.Since(batchStart))
+		}
+	}()
+
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			log.Info("Batch request cancelled due to shutdown", "workerID", w.id)
+			return
+		}
+		log.Error("SendBatch failed", "workerID", w.id, "port", w.proxy.Port, "error", err)
+		w.publish("batch_failed", err.Error())
+		w.eventLog.log(w.id, w.proxy.ID, "SendBatch failed", map[string]any{"error": err.Error()})
+		shouldBlockProxy = true
+		roundSucceeded = false
+	} else {
+		log.Info("SendBatch completed successfully",
+			"workerID", w.id,
+			"limit", w.batchLimit,
+			"responseCount", len(responses))
+
+		var failedCount int32
+		totalCount := len(responses)
+
+		var responseWg sync.WaitGroup
+		responseSem := semaphore.NewWeighted(int64(len(responses)))
+
+		for _, response := range responses {
+			responseWg.Add(1)
+			go func(resp cfbatch.BatchResponseLink) {
+				defer responseWg.Done()
+
+				select {
+				case <-w.rootCtx.Done():
+					return
+				default:
+				}
+
+				if err := responseSem.Acquire(w.rootCtx, 1); err != nil {
+					if err == context.Canceled {
+						log.Info("Response processing cancelled due to shutdown")
+						return
+					}
+					log.Error("Failed to acquire response semaphore", "error", err)
+					return
+				}
+				defer responseSem.Release(1)
+
+				if !resp.Status {
+					atomic.AddInt32(&failedCount, 1)
+				}
+
+				if resp.Link != nil {
+					w.playGame(resp, userAgent, spinLimiter)
+				}
+			}(response)
+		}
+
+		log.Info("Waiting for all response processing to complete", "totalResponses", totalCount)
+		responseWg.Wait()
+		log.Info("All response processing completed")
+
+		if totalCount > 0 {
+			finalFailedCount := atomic.LoadInt32(&failedCount)
+			failureRate := float64(finalFailedCount) / float64(totalCount)
+			successRate := 1 - failureRate
+			roundSucceeded = failureRate < 0.5
+			log.Info("Batch response analysis",
+				"workerID", w.id,
+				"totalResponses", totalCount,
+				"failedResponses", finalFailedCount,
+				"failureRate", fmt.Sprintf("%.2f%%", failureRate*100))
+
+			shouldBlock := w.tracker.record(w.proxy.ID, successRate)
+			if w.metrics != nil {
+				if rate, ok := w.tracker.successRateOf(w.proxy.ID); ok {
+					w.metrics.ewmaSuccessRate.WithLabelValues(w.proxy.ID).Set(rate)
+				}
+			}
+			if shouldBlock {
+				shouldBlockProxy = true
+				log.Warn("Proxy success rate EWMA below threshold, will block proxy",
+					"workerID", w.id,
+					"port", w.proxy.Port,
+					"failureRate", fmt.Sprintf("%.2f%%", failureRate*100))
+			}
+		}
+	}
+
+	w.mu.Lock()
+	w.round++
+	w.mu.Unlock()
+
+	if shouldBlockProxy {
+		blockCtx, blockCancel := context.WithTimeout(w.rootCtx, 30*time.Second)
+		_, blockErr := w.yarunClient.BlockProxy(blockCtx, w.proxy.ID)
+		blockCancel()
+
+		if blockErr != nil {
+			if blockCtx.Err() == context.Canceled {
+				log.Info("Block proxy cancelled due to shutdown", "workerID", w.id)
+				return
+			}
+			log.Error("Failed to block proxy", "workerID", w.id, "port", w.proxy.Port, "error", blockErr)
+		} else {
+			reason := "EWMA success rate below threshold"
+			if err != nil {
+				reason = "API error"
+			}
+			log.Info("Proxy blocked", "workerID", w.id, "port", w.proxy.Port, "reason", reason)
+			w.publish("proxy_blocked", reason)
+			w.eventLog.log(w.id, w.proxy.ID, "Proxy blocked", map[string]any{"reason": reason})
+			if w.metrics != nil {
+				w.metrics.proxyBlocksTotal.WithLabelValues(reason).Inc()
+			}
+		}
+	}
+}
+
+// playGame loads a pragmatic play session for resp and spins it to
+// completion, the same state machine the inline goroutine in Run used
+// to run directly.
+func (w *worker) playGame(resp cfbatch.BatchResponseLink, userAgent string, spinLimiter *rate.Limiter) {
+	var index *int
+	var counter *int
+
+	pp := pragmatic.NewPragmaticPlay(w.rootCtx, *resp.Link, userAgent)
+	if w.wgDialer != nil {
+		pp.SetDialContext(w.wgDialer.DialContext)
+	}
+
+	sessionData, err := pp.LoadSession()
+	if err != nil {
+		log.Error("error on load game", "error", err)
+		return
+	}
+
+	if sessionData == nil {
+		log.Error("error on load game: sessionData is nil")
+		return
+	}
+
+	initResData, err := pp.DoInit(sessionData.MGCKey, resp.GameSymbol)
+	if err != nil {
+		log.Error("error on init game", "error", err)
+		return
+	}
+
+	tmpIndex := initResData.Index + 1
+	index = &tmpIndex
+	tmpCounter := initResData.Counter + 1
+	counter = &tmpCounter
+
+	log.Info("user info", "balance", initResData.Balance, "total_win", initResData.TotalWin, "next_action", initResData.NextAction)
+
+	for {
+		select {
+		case <-w.rootCtx.Done():
+			log.Info("Shutdown requested, stopping game loop")
+			return
+		default:
+		}
+
+		w.waitIfPaused()
+
+		if initResData.NextAction == "s" && (initResData.Balance <= 500.0 || initResData.Balance >= 100_000.0) {
+			log.Info("threshold reached, stopping loop", "balance", initResData.Balance)
+
+			if initResData.Balance >= 100_000.0 {
+				log.Info("JACKPOT", "balance", initResData.Balance)
+				w.publish("jackpot", fmt.Sprintf("balance %.2f", initResData.Balance))
+				w.eventLog.log(w.id, w.proxy.ID, "JACKPOT", map[string]any{"game": resp.GameSymbol, "balance": initResData.Balance})
+				if w.metrics != nil {
+					w.metrics.jackpotsTotal.WithLabelValues(resp.GameSymbol).Inc()
+				}
+			}
+
+			updateCtx, updateCancel := context.WithTimeout(w.rootCtx, 30*time.Second)
+			_, err := w.yarunClient.UpdateUserBalance(updateCtx, resp.ID, initResData.Balance, resp.Coin)
+			updateCancel()
+			if err != nil {
+				if updateCtx.Err() == context.Canceled {
+					log.Info("Update balance cancelled due to shutdown")
+					return
+				}
+				log.Error("error on update user balance", "error", err)
+				return
+			}
+
+			break
+		}
+
+		balance := initResData.Balance
+		amount := 400.0
+		if balance > 10000 && balance <= 30000 {
+			amount = 600.0
+		} else if balance > 30000 && balance <= 50000 {
+			amount = 800.0
+		} else if balance > 50000 && balance <= 100000 {
+			amount = 1000.0
+		}
+
+		coinValue := int(amount / 20.0)
+		coin := &coinValue
+
+		if initResData.NextAction == "s" {
+			spinStart := time.Now()
+			respData, err := pp.DoSpin(sessionData.MGCKey, resp.GameSymbol, *coin, *index, *counter, "aq")
+			if w.metrics != nil {
+				w.metrics.spinDuration.Observe(time.Since(spinStart).Seconds())
+			}
+			if err != nil {
+				log.Error("error on spin game", "error", err)
+				w.publish("spin_failed", err.Error())
+				w.eventLog.log(w.id, w.proxy.ID, "spin failed", map[string]any{"game": resp.GameSymbol, "error": err.Error()})
+				if w.metrics != nil {
+					w.metrics.spinsTotal.WithLabelValues(resp.GameSymbol, "error").Inc()
+				}
+				return
+			}
+			if w.metrics != nil {
+				w.metrics.spinsTotal.WithLabelValues(resp.GameSymbol, "success").Inc()
+			}
+
+			tmpIndex = respData.Index + 1
+			index = &tmpIndex
+			tmpCounter = respData.Counter + 1
+			counter = &tmpCounter
+
+			log.Info("spin action info", "balance", respData.Balance, "total_win", respData.TotalWin, "new_index", *index, "new_counter", *counter, "next_action", respData.NextAction, "coin", *coin, "amount", amount)
+			w.eventLog.log(w.id, w.proxy.ID, "spin action info", map[string]any{"game": resp.GameSymbol, "balance": respData.Balance, "total_win": respData.TotalWin, "next_action": respData.NextAction})
+			initResData = respData
+		}
+
+		if initResData.NextAction == "c" {
+			respData, err := pp.DoCollect(sessionData.MGCKey, resp.GameSymbol, *index, *counter)
+			if err != nil {
+				log.Error("error on collect", "error", err)
+				return
+			}
+			if w.metrics != nil {
+				w.metrics.collectsTotal.WithLabelValues(resp.GameSymbol).Inc()
+			}
+
+			tmpIndex = respData.Index + 1
+			index = &tmpIndex
+			tmpCounter = respData.Counter + 1
+			counter = &tmpCounter
+
+			log.Info("collect action info", "balance", respData.Balance, "total_win", respData.TotalWin, "new_index", *index, "new_counter", *counter, "next_action", respData.NextAction)
+			w.eventLog.log(w.id, w.proxy.ID, "collect action info", map[string]any{"game": resp.GameSymbol, "balance": respData.Balance, "total_win": respData.TotalWin, "next_action": respData.NextAction})
+			initResData = respData
+		}
+
+		if err := spinLimiter.Wait(w.rootCtx); err != nil {
+			log.Info("Shutdown requested, stopping game loop")
+			return
+		}
+	}
+}
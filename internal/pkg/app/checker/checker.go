@@ -2,14 +2,23 @@ package checker
 
 import (
 	"bufio"
+	"context"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/time/rate"
+
+	"dayusch/internal/pkg/progress"
+	"dayusch/internal/pkg/useragent"
 )
 
 // SiteInfo represents the structure of the response from /__dayu/siteInfo.html
@@ -26,27 +35,81 @@ type SiteInfo struct {
 	IpaDownloadUrl     string            `json:"ipaDownloadUrl"`
 }
 
+// TLSInfo is the subset of a response's TLS handshake worth recording per
+// domain: who issued the certificate, what it covers, and when it expires.
+type TLSInfo struct {
+	SAN      []string  `json:"san,omitempty"`
+	Issuer   string    `json:"issuer,omitempty"`
+	NotAfter time.Time `json:"not_after,omitempty"`
+}
+
 // CheckResult represents the result of checking a domain
 type CheckResult struct {
-	Domain  string
-	Success bool
-	Error   string
+	Domain         string           `json:"domain"`
+	Success        bool             `json:"success"`
+	Error          string           `json:"error,omitempty"`
+	Attempts       int              `json:"attempts"`
+	StatusCode     int              `json:"status_code,omitempty"`
+	SiteInfo       *SiteInfo        `json:"site_info,omitempty"`
+	TLS            *TLSInfo         `json:"tls,omitempty"`
+	ResponseTimeMs int64            `json:"response_time_ms"`
+	RedirectChain  []string         `json:"redirect_chain,omitempty"`
+	Proxy          string           `json:"proxy,omitempty"`
+	Fingerprint    *SiteFingerprint `json:"fingerprint,omitempty"`
+
+	scheme    string // which scheme produced this result; used by enrich, not marshaled
+	usedProxy Proxy  // which proxy produced this result; used by enrich, not marshaled
+	viaProxy  bool
 }
 
 // Checker handles the domain checking functionality
 type Checker struct {
-	httpClient *http.Client
-	workers    int
+	httpClient      *http.Client
+	workers         int
+	retryPolicy     *RetryPolicy
+	dialer          *net.Dialer
+	network         string // "", "tcp4" or "tcp6"; empty lets the OS pick
+	disableHTTP2    bool
+	initialScheme   string // "https" or "http"; defaults to "https"
+	roundTripper    http.RoundTripper
+	proxyPool       *ProxyPool
+	schedulerConfig SchedulerConfig
+	progressChan    chan<- ProgressSnapshot
 }
 
-// NewChecker creates a new checker instance
-func NewChecker(workers int) *Checker {
-	return &Checker{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		workers: workers,
+// NewChecker creates a new checker instance.
+func NewChecker(workers int, opts ...Option) *Checker {
+	c := &Checker{
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		workers:       workers,
+		dialer:        &net.Dialer{Timeout: 10 * time.Second},
+		initialScheme: "https",
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	c.httpClient.Transport = c.buildTransport()
+	c.httpClient.CheckRedirect = checkRedirectRecorder
+
+	return c
+}
+
+// NewCheckerWithProxies builds a Checker that dials every request through a
+// proxy drawn from pool, recording which proxy served each domain in
+// CheckResult.Proxy and feeding the outcome back into pool's health scoring.
+func NewCheckerWithProxies(pool *ProxyPool, workers int, opts ...Option) *Checker {
+	c := NewChecker(workers, opts...)
+	c.proxyPool = pool
+	return c
+}
+
+// SetRetryPolicy enables retries of transient failures (network-level
+// errors and 5xx responses) with jittered exponential backoff. Passing nil
+// disables retries (the default): a single attempt per domain.
+func (c *Checker) SetRetryPolicy(policy *RetryPolicy) {
+	c.retryPolicy = policy
 }
 
 // ReadDomains reads domains from input file
@@ -73,99 +136,407 @@ func (c *Checker) ReadDomains(inputFile string) ([]string, error) {
 	return domains, nil
 }
 
-// CheckDomain checks a single domain for the presence of operate_area field
-func (c *Checker) CheckDomain(domain string) CheckResult {
-	url := fmt.Sprintf("https://%s/__dayu/siteInfo.html", domain)
+// redirectChainKey threads a per-request redirect chain through
+// http.Client.CheckRedirect via the request context, since the Checker's
+// http.Client (and its CheckRedirect func) is shared across concurrent
+// domains.
+type redirectChainKey struct{}
+
+func checkRedirectRecorder(req *http.Request, via []*http.Request) error {
+	if chain, ok := req.Context().Value(redirectChainKey{}).(*[]string); ok {
+		*chain = append(*chain, req.URL.String())
+	}
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after %d redirects", len(via))
+	}
+	return nil
+}
+
+// CheckDomain checks a single domain for the presence of operate_area,
+// retrying transient failures (network-level errors, 5xx responses) under
+// c.retryPolicy if SetRetryPolicy was called, and enriching a successful
+// result with a second, UA/Accept-Language-aware request.
+func (c *Checker) CheckDomain(ctx context.Context, domain string) CheckResult {
+	if c.retryPolicy == nil {
+		result, _ := c.probeWithSchemeFallback(ctx, domain)
+		result.Attempts = 1
+		return result
+	}
+
+	policy := *c.retryPolicy
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		result, class := c.probeWithSchemeFallback(ctx, domain)
+		result.Attempts = attempt
+
+		if class != classTransient || attempt >= policy.MaxAttempts || time.Since(start) > policy.RetryTimeout {
+			return result
+		}
+
+		select {
+		case <-ctx.Done():
+			result.Error = ctx.Err().Error()
+			return result
+		case <-time.After(backoffDuration(policy, attempt)):
+		}
+	}
+}
+
+// probeWithSchemeFallback tries c.initialScheme first and, on a transient
+// (connection-level) failure, falls back to the other scheme within the
+// same attempt before handing the outcome to the retry loop.
+func (c *Checker) probeWithSchemeFallback(ctx context.Context, domain string) (CheckResult, errorClass) {
+	schemes := []string{c.initialScheme, otherScheme(c.initialScheme)}
+
+	var result CheckResult
+	var class errorClass
+
+	for i, scheme := range schemes {
+		result, class = c.checkDomainOnce(ctx, domain, scheme)
+		if class != classTransient || i == len(schemes)-1 {
+			break
+		}
+	}
+
+	if class == classFatal && result.Success {
+		c.enrich(ctx, domain, result.scheme, result.usedProxy, result.viaProxy, result.SiteInfo, &result)
+		result.Fingerprint = c.buildFingerprint(ctx, domain, result.scheme, result.SiteInfo)
+	}
+
+	return result, class
+}
+
+func otherScheme(scheme string) string {
+	if scheme == "http" {
+		return "https"
+	}
+	return "http"
+}
+
+// checkDomainOnce performs a single, non-retried attempt at checking domain
+// over scheme and classifies the outcome for the retry loop in CheckDomain.
+func (c *Checker) checkDomainOnce(ctx context.Context, domain, scheme string) (CheckResult, errorClass) {
+	url := fmt.Sprintf("%s://%s/__dayu/siteInfo.html", scheme, domain)
+
+	var chain []string
+	reqCtx := context.WithValue(ctx, redirectChainKey{}, &chain)
+
+	var usedProxy Proxy
+	var viaProxy bool
+	if c.proxyPool != nil {
+		if p, err := c.proxyPool.Pick(); err == nil {
+			usedProxy, viaProxy = p, true
+			reqCtx = withProxy(reqCtx, p)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return CheckResult{Domain: domain, Success: false, Error: fmt.Sprintf("failed to build request: %v", err), scheme: scheme}, classFatal
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	elapsed := time.Since(start)
+
+	reportProxyResult := func(success bool) {
+		if viaProxy {
+			c.proxyPool.ReportResult(usedProxy, success, elapsed)
+		}
+	}
 
-	resp, err := c.httpClient.Get(url)
 	if err != nil {
+		reportProxyResult(false)
 		return CheckResult{
-			Domain:  domain,
-			Success: false,
-			Error:   fmt.Sprintf("HTTP request failed: %v", err),
-		}
+			Domain:         domain,
+			Success:        false,
+			Error:          fmt.Sprintf("%s: HTTP request failed: %v", scheme, err),
+			ResponseTimeMs: elapsed.Milliseconds(),
+			RedirectChain:  chain,
+			Proxy:          proxyLabel(viaProxy, usedProxy),
+			scheme:         scheme,
+			usedProxy:      usedProxy,
+			viaProxy:       viaProxy,
+		}, classifyError(err)
 	}
 	defer resp.Body.Close()
 
+	result := CheckResult{
+		Domain:         domain,
+		StatusCode:     resp.StatusCode,
+		ResponseTimeMs: elapsed.Milliseconds(),
+		RedirectChain:  chain,
+		TLS:            tlsInfoFrom(resp),
+		Proxy:          proxyLabel(viaProxy, usedProxy),
+		scheme:         scheme,
+		usedProxy:      usedProxy,
+		viaProxy:       viaProxy,
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return CheckResult{
-			Domain:  domain,
-			Success: false,
-			Error:   fmt.Sprintf("HTTP status: %d", resp.StatusCode),
-		}
+		result.Error = fmt.Sprintf("HTTP status: %d", resp.StatusCode)
+		reportProxyResult(false)
+		return result, classifyStatus(resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return CheckResult{
-			Domain:  domain,
-			Success: false,
-			Error:   fmt.Sprintf("Failed to read response body: %v", err),
-		}
+		result.Error = fmt.Sprintf("failed to read response body: %v", err)
+		reportProxyResult(false)
+		return result, classifyError(err)
 	}
 
 	var siteInfo SiteInfo
 	if err := json.Unmarshal(body, &siteInfo); err != nil {
-		return CheckResult{
-			Domain:  domain,
-			Success: false,
-			Error:   fmt.Sprintf("Failed to parse JSON: %v", err),
-		}
+		result.Error = fmt.Sprintf("failed to parse JSON: %v", err)
+		reportProxyResult(false)
+		return result, classFatal
 	}
 
-	// Check if operate_area field exists and is not empty
 	if siteInfo.OperateArea == "" {
-		return CheckResult{
-			Domain:  domain,
-			Success: false,
-			Error:   "operate_area field is missing or empty",
-		}
+		result.Error = "operate_area field is missing or empty"
+		reportProxyResult(false)
+		return result, classFatal
+	}
+
+	result.Success = true
+	result.SiteInfo = &siteInfo
+	reportProxyResult(true)
+	return result, classFatal
+}
+
+// tlsInfoFrom extracts the leaf certificate's SAN and issuer from resp, or
+// nil for a plain HTTP response.
+func tlsInfoFrom(resp *http.Response) *TLSInfo {
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+
+	cert := resp.TLS.PeerCertificates[0]
+	san := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		san = append(san, ip.String())
+	}
+
+	return &TLSInfo{
+		SAN:      san,
+		Issuer:   issuerCN(cert),
+		NotAfter: cert.NotAfter,
+	}
+}
+
+func issuerCN(cert *x509.Certificate) string {
+	if cert.Issuer.CommonName != "" {
+		return cert.Issuer.CommonName
+	}
+	return cert.Issuer.String()
+}
+
+// acceptLanguageFor derives an Accept-Language header value from a
+// siteInfo.MainLanguage code, e.g. "id" -> "id-ID,id;q=0.9".
+func acceptLanguageFor(lang string) string {
+	if lang == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s-%s,%s;q=0.9", lang, strings.ToUpper(lang), lang)
+}
+
+// enrich re-requests domain (through the same proxy as the first pass, if
+// any) with a rotated UA and an Accept-Language derived from the first
+// pass's SiteInfo.MainLanguage, mimicking a real browser rather than Go's
+// default client, and overlays the response metadata (status, TLS, timing,
+// redirects) onto result. Enrichment errors are non-fatal: the first-pass
+// result already stands on its own.
+func (c *Checker) enrich(ctx context.Context, domain, scheme string, usedProxy Proxy, viaProxy bool, info *SiteInfo, result *CheckResult) {
+	if info == nil {
+		return
+	}
+
+	url := fmt.Sprintf("%s://%s/__dayu/siteInfo.html", scheme, domain)
+
+	var chain []string
+	reqCtx := context.WithValue(ctx, redirectChainKey{}, &chain)
+	if viaProxy {
+		reqCtx = withProxy(reqCtx, usedProxy)
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("User-Agent", useragent.GetNextUserAgent())
+	if al := acceptLanguageFor(info.MainLanguage); al != "" {
+		req.Header.Set("Accept-Language", al)
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	elapsed := time.Since(start)
+	if viaProxy {
+		c.proxyPool.ReportResult(usedProxy, err == nil, elapsed)
 	}
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
 
-	return CheckResult{
-		Domain:  domain,
-		Success: true,
-		Error:   "",
+	result.StatusCode = resp.StatusCode
+	result.ResponseTimeMs = elapsed.Milliseconds()
+	result.RedirectChain = chain
+	if tls := tlsInfoFrom(resp); tls != nil {
+		result.TLS = tls
 	}
 }
 
-// CheckDomains checks multiple domains concurrently
-func (c *Checker) CheckDomains(domains []string) []CheckResult {
-	jobs := make(chan string, len(domains))
-	results := make(chan CheckResult, len(domains))
+// CheckDomains checks domains under an adaptive scheduler: a token-bucket
+// global rate limit, a per-host concurrency cap (see SchedulerConfig), and
+// AIMD worker sizing that grows by one worker on a sustained streak of
+// clean completions and halves on a 429/5xx-classified one. Results stream
+// to w as a JSONL record as soon as each completes, instead of buffering the
+// whole set in memory; onResult, if non-nil, is invoked with every result
+// right after it's written. ctx cancellation (e.g. SIGINT in the CLI) stops
+// scheduling of domains that haven't started yet, but lets in-flight checks
+// finish and still be written, so a mid-run cancellation doesn't drop
+// partial results. noProgress suppresses the built-in progress bar;
+// WithProgressChannel additionally streams a ProgressSnapshot per result.
+func (c *Checker) CheckDomains(ctx context.Context, domains []string, w io.Writer, noProgress bool, onResult func(CheckResult)) error {
+	cfg := c.schedulerConfig
+	if cfg.MaxWorkers <= 0 {
+		cfg = DefaultSchedulerConfig(c.workers)
+	}
+
+	var limiter *rate.Limiter
+	if cfg.QPS > 0 {
+		burst := int(cfg.QPS)
+		if burst < 1 {
+			burst = 1
+		}
+		limiter = rate.NewLimiter(rate.Limit(cfg.QPS), burst)
+	}
+
+	hosts := newHostSemaphore(cfg.PerHostLimit)
+	aimd := newAIMDController(cfg.MinWorkers, cfg.MaxWorkers)
+	metrics := &metricsTracker{}
+
+	bar := progress.New("Checking domains", len(domains), progress.WithSilent(noProgress))
+	defer bar.Finish()
+
+	jobs := make(chan string)
+	go func() {
+		defer close(jobs)
+		for _, domain := range domains {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- domain:
+			}
+		}
+	}()
+
+	var (
+		wg          sync.WaitGroup
+		encMu       sync.Mutex
+		enc         = json.NewEncoder(w)
+		liveWorkers int32
+		inFlight    int32
+		done        int32
+		errOnce     sync.Once
+		firstErr    error
+	)
 
-	// Start workers
-	var wg sync.WaitGroup
-	for i := 0; i < c.workers; i++ {
+	var spawn func()
+	spawn = func() {
+		atomic.AddInt32(&liveWorkers, 1)
 		wg.Add(1)
+
 		go func() {
 			defer wg.Done()
-			for domain := range jobs {
-				result := c.CheckDomain(domain)
-				results <- result
+			defer atomic.AddInt32(&liveWorkers, -1)
+
+			for {
+				// A capacity shrink (AIMD multiplicative decrease) leaves more
+				// live workers than capacity; self-select out here rather than
+				// tracking which specific worker to retire.
+				if int(atomic.LoadInt32(&liveWorkers)) > aimd.capacity() {
+					return
+				}
+
+				var domain string
+				select {
+				case <-ctx.Done():
+					return
+				case d, ok := <-jobs:
+					if !ok {
+						return
+					}
+					domain = d
+				}
+
+				if limiter != nil {
+					if err := limiter.Wait(ctx); err != nil {
+						return
+					}
+				}
+
+				release, err := hosts.acquire(ctx, domain)
+				if err != nil {
+					return
+				}
+
+				atomic.AddInt32(&inFlight, 1)
+				start := time.Now()
+				result := c.CheckDomain(ctx, domain)
+				elapsed := time.Since(start)
+				atomic.AddInt32(&inFlight, -1)
+				release()
+
+				throttled := result.StatusCode == http.StatusTooManyRequests || result.StatusCode >= 500
+				ewmaLatency, errorRate := metrics.record(elapsed, result.Success)
+				if aimd.recordResult(throttled) {
+					spawn()
+				}
+
+				encMu.Lock()
+				encErr := enc.Encode(result)
+				encMu.Unlock()
+				if encErr != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("failed to write result for %s: %w", domain, encErr)
+					})
+				}
+
+				if onResult != nil {
+					onResult(result)
+				}
+
+				doneN := atomic.AddInt32(&done, 1)
+				bar.Update(int(doneN), len(domains))
+
+				if c.progressChan != nil {
+					select {
+					case c.progressChan <- ProgressSnapshot{
+						Done:        int(doneN),
+						Total:       len(domains),
+						InFlight:    int(atomic.LoadInt32(&inFlight)),
+						EWMALatency: ewmaLatency,
+						ErrorRate:   errorRate,
+					}:
+					default:
+					}
+				}
 			}
 		}()
 	}
 
-	// Send jobs
-	for _, domain := range domains {
-		jobs <- domain
+	for i := 0; i < aimd.capacity(); i++ {
+		spawn()
 	}
-	close(jobs)
 
-	// Wait for all workers to finish
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	// Collect results
-	var allResults []CheckResult
-	for result := range results {
-		allResults = append(allResults, result)
-	}
+	wg.Wait()
 
-	return allResults
+	return firstErr
 }
 
 // WriteSuccessfulDomains writes successful domains to output file
@@ -0,0 +1,163 @@
+package checker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// configPaths are adjacent well-known paths probed for a domain's
+// TemplateCode/GameApiID, tried in order since different deployments of the
+// same Dayu template expose the same data under different paths.
+var configPaths = []string{"/__dayu/config.json", "/api/site/info"}
+
+// ConfigInfo is the subset of /__dayu/config.json (or its /api/site/info
+// fallback) worth fingerprinting.
+type ConfigInfo struct {
+	GameApiID string `json:"gameApiId"`
+	GameID    string `json:"gameId"`
+}
+
+// SiteFingerprint summarizes everything CheckDomain gathered about a
+// domain's deployment: template identity, Turnstile presence, and hashes of
+// its robots.txt/favicon, collapsed into a single ContentHash so mirrors of
+// the same template content are trivially deduplicatable.
+type SiteFingerprint struct {
+	TemplateCode        string `json:"template_code,omitempty"`
+	OperateArea         string `json:"operate_area,omitempty"`
+	GameApiID           string `json:"game_api_id,omitempty"`
+	CfTurnstileDetected bool   `json:"cf_turnstile_detected"`
+	RobotsHash          string `json:"robots_hash,omitempty"`
+	FaviconHash         string `json:"favicon_hash,omitempty"`
+	ContentHash         string `json:"content_hash"`
+}
+
+// buildFingerprint probes the adjacent well-known paths and derives a
+// SiteFingerprint from info plus those probes. info must be non-nil (the
+// first pass already confirmed OperateArea is present); probe failures are
+// non-fatal and just leave the corresponding field empty.
+func (c *Checker) buildFingerprint(ctx context.Context, domain, scheme string, info *SiteInfo) *SiteFingerprint {
+	if info == nil {
+		return nil
+	}
+
+	fp := &SiteFingerprint{
+		TemplateCode:        info.TemplateCode,
+		OperateArea:         info.OperateArea,
+		CfTurnstileDetected: info.CfTurnstileSiteKey != "" || (info.CfTurnstileSwitch != "" && info.CfTurnstileSwitch != "0"),
+	}
+
+	if cfg := c.fetchConfigInfo(ctx, domain, scheme); cfg != nil {
+		fp.GameApiID = cfg.GameApiID
+	}
+
+	fp.RobotsHash = c.hashAuxPath(ctx, domain, scheme, "/robots.txt")
+	fp.FaviconHash = c.hashAuxPath(ctx, domain, scheme, "/favicon.ico")
+	fp.ContentHash = fp.contentHash()
+
+	return fp
+}
+
+// contentHash derives a stable sha256 fingerprint from every field gathered
+// about the domain's deployment.
+func (fp *SiteFingerprint) contentHash() string {
+	parts := []string{
+		fp.TemplateCode,
+		fp.OperateArea,
+		fp.GameApiID,
+		strconv.FormatBool(fp.CfTurnstileDetected),
+		fp.RobotsHash,
+		fp.FaviconHash,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// fetchConfigInfo tries each of configPaths in turn, returning the first one
+// that decodes into a non-empty ConfigInfo.
+func (c *Checker) fetchConfigInfo(ctx context.Context, domain, scheme string) *ConfigInfo {
+	for _, path := range configPaths {
+		body, err := c.fetchAuxPath(ctx, domain, scheme, path)
+		if err != nil {
+			continue
+		}
+
+		var cfg ConfigInfo
+		if err := json.Unmarshal(body, &cfg); err == nil && (cfg.GameApiID != "" || cfg.GameID != "") {
+			return &cfg
+		}
+	}
+	return nil
+}
+
+// hashAuxPath fetches path and returns a hex sha256 of its body, or an empty
+// string if the fetch failed.
+func (c *Checker) hashAuxPath(ctx context.Context, domain, scheme, path string) string {
+	body, err := c.fetchAuxPath(ctx, domain, scheme, path)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// fetchAuxPath issues a best-effort GET for a fingerprinting probe, reusing
+// c.httpClient's transport settings. ctx carries no proxy selection, so the
+// probe dials directly rather than through a pool proxy, and it doesn't
+// affect proxy health scoring: these are supplementary probes, not the
+// primary availability check.
+func (c *Checker) fetchAuxPath(ctx context.Context, domain, scheme, path string) ([]byte, error) {
+	url := fmt.Sprintf("%s://%s%s", scheme, domain, path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: HTTP status %d", path, resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// clusterKey derives a stable grouping key from a fingerprint's template
+// identity, so Cluster doesn't have to reach into SiteFingerprint's other,
+// more volatile probe fields (robots/favicon hash) to decide two domains
+// belong to the same operator.
+func clusterKey(templateCode, operateArea, gameApiID string) string {
+	sum := sha256.Sum256([]byte(templateCode + "|" + operateArea + "|" + gameApiID))
+	return hex.EncodeToString(sum[:])
+}
+
+// Cluster groups successful results by TemplateCode+OperateArea+GameApiID,
+// so a single operator running many mirrors (e.g. the same Dayu template
+// under different domains) surfaces as one cluster instead of N independent
+// hits. Results without a Fingerprint (failed checks) are skipped.
+func (c *Checker) Cluster(results []CheckResult) map[string][]string {
+	clusters := make(map[string][]string)
+
+	for _, r := range results {
+		if !r.Success || r.Fingerprint == nil {
+			continue
+		}
+
+		key := clusterKey(r.Fingerprint.TemplateCode, r.Fingerprint.OperateArea, r.Fingerprint.GameApiID)
+		clusters[key] = append(clusters[key], r.Domain)
+	}
+
+	return clusters
+}
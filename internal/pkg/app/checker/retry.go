@@ -0,0 +1,103 @@
+package checker
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// errorClass groups a CheckDomain failure into a retry decision.
+type errorClass int
+
+const (
+	classFatal errorClass = iota
+	classTransient
+)
+
+// RetryPolicy controls how CheckDomain retries transient failures
+// (network-level errors such as DNS, TLS, timeout or refused-connection
+// failures, and 5xx responses) with jittered exponential backoff, using the
+// same backoff shape as pragmatic.RetryPolicy.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	RetryTimeout   time.Duration // cumulative wall-clock budget across all attempts
+	Jitter         float64       // fraction of the backoff to randomize, e.g. 0.2
+}
+
+// DefaultRetryPolicy is a conservative policy suitable for most deployments.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	RetryTimeout:   60 * time.Second,
+	Jitter:         0.2,
+}
+
+// classifyError inspects the error produced by an HTTP round trip and
+// decides whether it's worth retrying: any network-level error (a net.Error,
+// which covers every error http.Client.Do returns: DNS resolution and TLS
+// handshake failures, timeouts, refused or reset connections) is transient;
+// a successfully parsed response (bad status, bad JSON, missing field) is
+// not, since retrying won't change the outcome.
+func classifyError(err error) errorClass {
+	if err == nil {
+		return classFatal
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return classTransient
+	}
+
+	var tlsErr *tls.RecordHeaderError
+	if errors.As(err, &tlsErr) {
+		return classTransient
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return classTransient
+	}
+
+	var jsonErr *json.SyntaxError
+	if errors.As(err, &jsonErr) {
+		return classFatal
+	}
+
+	return classFatal
+}
+
+// classifyStatus decides whether an HTTP status code is worth retrying.
+// Only server errors (5xx) are treated as transient; client errors and
+// redirects are assumed to be stable for the lifetime of a single run.
+func classifyStatus(status int) errorClass {
+	if status >= 500 {
+		return classTransient
+	}
+	return classFatal
+}
+
+// backoffDuration computes the jittered exponential backoff for attempt
+// (1-indexed) under policy.
+func backoffDuration(policy RetryPolicy, attempt int) time.Duration {
+	d := policy.InitialBackoff << (attempt - 1)
+	if d <= 0 || d > policy.MaxBackoff {
+		d = policy.MaxBackoff
+	}
+
+	if policy.Jitter <= 0 {
+		return d
+	}
+
+	delta := time.Duration(float64(d) * policy.Jitter)
+	if delta <= 0 {
+		return d
+	}
+
+	return d - delta/2 + time.Duration(rand.Int63n(int64(delta)))
+}
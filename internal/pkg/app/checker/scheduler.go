@@ -0,0 +1,173 @@
+package checker
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchedulerConfig tunes CheckDomains' adaptive scheduler: a global
+// token-bucket rate limit, a per-host concurrency cap, and the bounds AIMD
+// worker sizing grows/shrinks within.
+type SchedulerConfig struct {
+	QPS          float64 // global token-bucket rate; <= 0 disables the limit
+	MinWorkers   int
+	MaxWorkers   int
+	PerHostLimit int // max concurrent requests per host; <= 0 disables the cap
+}
+
+// DefaultSchedulerConfig sizes MinWorkers/MaxWorkers around workers (the
+// Checker's configured worker count), with no rate limit or per-host cap.
+func DefaultSchedulerConfig(workers int) SchedulerConfig {
+	if workers <= 0 {
+		workers = 10
+	}
+	return SchedulerConfig{MinWorkers: workers, MaxWorkers: workers * 4}
+}
+
+// ProgressSnapshot is a point-in-time view of CheckDomains' progress, sent
+// on the channel registered via WithProgressChannel so a CLI can render its
+// own live progress bar (in addition to the built-in one).
+type ProgressSnapshot struct {
+	Done        int
+	Total       int
+	InFlight    int
+	EWMALatency time.Duration
+	ErrorRate   float64
+}
+
+// aimdGrowStreak is how many consecutive non-throttled completions the
+// worker pool needs before growing by one worker.
+const aimdGrowStreak = 5
+
+// aimdController adjusts worker capacity within [min, max]: additive
+// increase by one worker on a sustained streak of clean completions,
+// multiplicative decrease (halved) on a 429/5xx-classified completion. This
+// is the same congestion-avoidance rule TCP uses to share bandwidth without
+// a central coordinator.
+type aimdController struct {
+	mu            sync.Mutex
+	cur           int
+	min, max      int
+	consecutiveOK int
+}
+
+func newAIMDController(min, max int) *aimdController {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	return &aimdController{cur: min, min: min, max: max}
+}
+
+func (a *aimdController) capacity() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.cur
+}
+
+// recordResult applies the AIMD rule for one completed request and reports
+// whether capacity grew, so the caller knows to spawn an extra worker.
+func (a *aimdController) recordResult(throttled bool) (grew bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if throttled {
+		next := a.cur / 2
+		if next < a.min {
+			next = a.min
+		}
+		a.cur = next
+		a.consecutiveOK = 0
+		return false
+	}
+
+	a.consecutiveOK++
+	if a.consecutiveOK >= aimdGrowStreak && a.cur < a.max {
+		a.cur++
+		a.consecutiveOK = 0
+		return true
+	}
+
+	return false
+}
+
+// hostSemaphore caps concurrent requests per host, so many subdomains
+// belonging to one operator can't hammer a single origin even when the
+// global worker pool is large.
+type hostSemaphore struct {
+	limit int
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func newHostSemaphore(limit int) *hostSemaphore {
+	return &hostSemaphore{limit: limit, slots: make(map[string]chan struct{})}
+}
+
+// acquire blocks until a slot for domain's host is free or ctx is done, and
+// returns a func to release it.
+func (h *hostSemaphore) acquire(ctx context.Context, domain string) (func(), error) {
+	if h.limit <= 0 {
+		return func() {}, nil
+	}
+
+	host := hostOf(domain)
+
+	h.mu.Lock()
+	slot, ok := h.slots[host]
+	if !ok {
+		slot = make(chan struct{}, h.limit)
+		h.slots[host] = slot
+	}
+	h.mu.Unlock()
+
+	select {
+	case slot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return func() { <-slot }, nil
+}
+
+// hostOf derives the per-host semaphore key from a domain, stripping a
+// leading "www." so a mirror and its bare/www counterpart share one cap.
+func hostOf(domain string) string {
+	return strings.TrimPrefix(domain, "www.")
+}
+
+// metricsTracker maintains the EWMA latency and error rate reported in
+// ProgressSnapshot.
+type metricsTracker struct {
+	mu          sync.Mutex
+	ewmaLatency float64 // milliseconds
+	errorRate   float64 // fraction in [0,1], EWMA-smoothed
+}
+
+const metricsEWMAAlpha = 0.2
+
+// record folds one completed request's latency and outcome into the
+// tracker and returns the updated snapshot values.
+func (m *metricsTracker) record(latency time.Duration, success bool) (ewmaLatency time.Duration, errorRate float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	latencyMs := float64(latency.Milliseconds())
+	if m.ewmaLatency == 0 {
+		m.ewmaLatency = latencyMs
+	} else {
+		m.ewmaLatency = metricsEWMAAlpha*latencyMs + (1-metricsEWMAAlpha)*m.ewmaLatency
+	}
+
+	errSample := 0.0
+	if !success {
+		errSample = 1.0
+	}
+	m.errorRate = metricsEWMAAlpha*errSample + (1-metricsEWMAAlpha)*m.errorRate
+
+	return time.Duration(m.ewmaLatency) * time.Millisecond, m.errorRate
+}
@@ -0,0 +1,159 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Option configures a Checker at construction time.
+type Option func(*Checker)
+
+// WithRequestTimeout bounds a single HTTP round trip (not counting
+// retries). The default is 30 seconds.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(c *Checker) { c.httpClient.Timeout = d }
+}
+
+// WithResolver overrides DNS resolution, e.g. to point at a specific
+// recursive resolver instead of the system default.
+func WithResolver(r *net.Resolver) Option {
+	return func(c *Checker) { c.dialer.Resolver = r }
+}
+
+// WithIPv4Only forces outbound connections over IPv4.
+func WithIPv4Only() Option {
+	return func(c *Checker) { c.network = "tcp4" }
+}
+
+// WithIPv6Only forces outbound connections over IPv6.
+func WithIPv6Only() Option {
+	return func(c *Checker) { c.network = "tcp6" }
+}
+
+// WithHTTP2Disabled forces HTTP/1.1, for hosts whose HTTP/2 stack
+// misbehaves (stalls, RST_STREAM floods, etc).
+func WithHTTP2Disabled() Option {
+	return func(c *Checker) { c.disableHTTP2 = true }
+}
+
+// WithInitialScheme sets which scheme ("https" or "http") CheckDomain
+// tries first before falling back to the other on a transient failure.
+// The default is "https".
+func WithInitialScheme(scheme string) Option {
+	return func(c *Checker) { c.initialScheme = scheme }
+}
+
+// WithSchedulerConfig overrides CheckDomains' adaptive scheduler tuning
+// (rate limit, per-host cap, worker bounds). Without it (or with MaxWorkers
+// <= 0), CheckDomains falls back to DefaultSchedulerConfig, sized around
+// NewChecker's workers argument with no rate limit or per-host cap.
+func WithSchedulerConfig(cfg SchedulerConfig) Option {
+	return func(c *Checker) { c.schedulerConfig = cfg }
+}
+
+// WithProgressChannel registers ch to receive a ProgressSnapshot after
+// every completed domain, in addition to the built-in progress bar. Sends
+// are non-blocking: a slow consumer just misses intermediate snapshots
+// rather than stalling the scheduler.
+func WithProgressChannel(ch chan<- ProgressSnapshot) Option {
+	return func(c *Checker) { c.progressChan = ch }
+}
+
+// WithRoundTripper overrides the transport entirely, bypassing the
+// dialer/resolver/IP-preference/HTTP2 options above. Tests use this to
+// inject a fake RoundTripper instead of making real network calls.
+func WithRoundTripper(rt http.RoundTripper) Option {
+	return func(c *Checker) { c.roundTripper = rt }
+}
+
+// buildTransport assembles c.httpClient.Transport from the dialer, network
+// preference, HTTP/2 toggle and per-request proxy (see withProxy), unless
+// WithRoundTripper supplied an override.
+func (c *Checker) buildTransport() http.RoundTripper {
+	if c.roundTripper != nil {
+		return c.roundTripper
+	}
+
+	transport := &http.Transport{
+		Proxy: c.proxyURLForRequest,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if c.network != "" {
+				network = c.network
+			}
+			if p, ok := proxyFromContext(ctx); ok && p.Scheme == "socks5" {
+				return dialSOCKS5(ctx, c.dialer, p, network, addr)
+			}
+			return c.dialer.DialContext(ctx, network, addr)
+		},
+		ForceAttemptHTTP2: !c.disableHTTP2,
+	}
+
+	if c.disableHTTP2 {
+		// An empty, non-nil TLSNextProto map disables the net/http package's
+		// automatic HTTP/2 upgrade for TLS connections.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return transport
+}
+
+// proxyContextKey threads the Proxy selected for a request (see
+// checkDomainOnce) through http.Transport.Proxy and buildTransport's
+// DialContext, mirroring how redirectChainKey threads per-request state
+// through CheckRedirect: the Checker's http.Client and Transport are shared
+// across concurrent domains, so per-request routing has to ride the
+// request's context instead of a Checker field.
+type proxyContextKey struct{}
+
+func withProxy(ctx context.Context, p Proxy) context.Context {
+	return context.WithValue(ctx, proxyContextKey{}, p)
+}
+
+func proxyFromContext(ctx context.Context) (Proxy, bool) {
+	p, ok := ctx.Value(proxyContextKey{}).(Proxy)
+	return p, ok
+}
+
+// proxyURLForRequest implements http.Transport.Proxy: it reads the proxy
+// chosen for req (if any) and builds the CONNECT target for HTTP/HTTPS
+// proxies. SOCKS5 proxies are handled in buildTransport's DialContext
+// instead, since net/http's Proxy hook only understands HTTP CONNECT
+// tunneling, so this returns nil for them.
+func (c *Checker) proxyURLForRequest(req *http.Request) (*url.URL, error) {
+	p, ok := proxyFromContext(req.Context())
+	if !ok || p.Scheme == "socks5" {
+		return nil, nil
+	}
+
+	u := &url.URL{Scheme: p.Scheme, Host: p.Address}
+	if p.Username != "" {
+		u.User = url.UserPassword(p.Username, p.Password)
+	}
+	return u, nil
+}
+
+// dialSOCKS5 dials addr through the SOCKS5 proxy p, falling back to forward
+// for the underlying TCP connection.
+func dialSOCKS5(ctx context.Context, forward *net.Dialer, p Proxy, network, addr string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if p.Username != "" {
+		auth = &proxy.Auth{User: p.Username, Password: p.Password}
+	}
+
+	dialer, err := proxy.SOCKS5(network, p.Address, auth, forward)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SOCKS5 dialer for %s: %w", p.Address, err)
+	}
+
+	if ctxDialer, ok := dialer.(proxy.ContextDialer); ok {
+		return ctxDialer.DialContext(ctx, network, addr)
+	}
+	return dialer.Dial(network, addr)
+}
@@ -2,25 +2,118 @@ package dupdel
 
 import (
 	"context"
-	"dayusch/internal/pkg/db"
-	"dayusch/internal/pkg/repo"
+	"fmt"
+	"net/http"
 	"os"
+	"sync"
 	"time"
 
+	"dayusch/internal/pkg/admin"
+	"dayusch/internal/pkg/db"
+	"dayusch/internal/pkg/repo"
+
 	"github.com/charmbracelet/log"
+	"github.com/gorilla/websocket"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// cursorCheckpointInterval is how many users are processed between state
+// checkpoints, so a crash mid-run resumes close to where it left off
+// instead of replaying the whole collection.
+const cursorCheckpointInterval = 50
+
+// lockTTL bounds how long a run's lock survives without being renewed: an
+// instance that crashes mid-run stops renewing, and another instance (or a
+// restarted one with the same run name) can take over after this elapses
+// instead of waiting forever.
+const lockTTL = 1 * time.Minute
+
+// lockRenewInterval is how often Run refreshes its own lock, comfortably
+// inside lockTTL so a slow checkpoint or GC pause doesn't cost the lock.
+const lockRenewInterval = lockTTL / 3
+
+// lockRetryInterval is how long Run waits between failed lock attempts
+// before another instance already holds runName's lock.
+const lockRetryInterval = 5 * time.Second
+
 type DupDel struct {
 	ctx context.Context
+
+	runName string
+	restart bool
+	owner   string
+
+	progress progress
+	logBus   *admin.LogBus
 }
 
-func NewDupDel(ctx context.Context) *DupDel {
+// NewDupDel configures a dedupe run named runName, the key checkpoints and
+// the distributed lock are stored under in the _dupdel_state collection.
+// Multiple dupdel processes sharing a run name coordinate via that lock, so
+// only one of them scans at a time; processes given distinct run names (and
+// presumably distinct ID ranges to scan) run concurrently without
+// conflicting. If restart is true, any existing checkpoint for runName is
+// discarded and the scan starts over from primitive.NilObjectID; otherwise
+// it resumes from wherever runName last left off.
+func NewDupDel(ctx context.Context, runName string, restart bool) *DupDel {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown-host"
+	}
+
 	return &DupDel{
-		ctx: ctx,
+		ctx:     ctx,
+		runName: runName,
+		restart: restart,
+		owner:   fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		logBus:  admin.NewLogBus(),
 	}
 }
 
+// Progress is a point-in-time view of Run's scan, returned by the
+// admin server's /api/dupdel/progress.
+type Progress struct {
+	RunName      string    `json:"run_name"`
+	LastIndex    string    `json:"last_index"`
+	TotalDeleted int       `json:"total_deleted"`
+	StartedAt    time.Time `json:"started_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type progress struct {
+	mu sync.Mutex
+	Progress
+}
+
+// init records the run's identity and start time once, before the first
+// update; unlike LastIndex/TotalDeleted/UpdatedAt it doesn't change per
+// checkpoint.
+func (p *progress) init(runName string, startedAt time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.RunName = runName
+	p.StartedAt = startedAt
+}
+
+func (p *progress) update(index primitive.ObjectID, totalDeleted int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.LastIndex = index.Hex()
+	p.TotalDeleted = totalDeleted
+	p.UpdatedAt = time.Now()
+}
+
+func (p *progress) snapshot() Progress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Progress
+}
+
+// Progress returns a snapshot of the current scan position.
+func (d *DupDel) Progress() Progress {
+	return d.progress.snapshot()
+}
+
 func (d *DupDel) Run() {
 	uri := os.Getenv("MONGO_URI")
 	dbName := os.Getenv("MONGO_DB")
@@ -32,12 +125,168 @@ func (d *DupDel) Run() {
 
 	ur := repo.NewUserRepo(md)
 
-	// Initialize with nil ObjectID (000000000000000000000000)
+	sr, err := newStateRepo(d.ctx, md)
+	if err != nil {
+		log.Fatal("failed to initialize dupdel state collection", "error", err)
+	}
+
+	if d.restart {
+		if err := sr.reset(d.ctx, d.runName); err != nil {
+			log.Error("failed to discard existing checkpoint for restart", "run", d.runName, "error", err)
+		} else {
+			log.Info("restarting run from scratch, existing checkpoint discarded", "run", d.runName)
+		}
+	}
+
+	// Initialize with nil ObjectID (000000000000000000000000), or resume
+	// from the last checkpointed user if one was saved by a prior run.
 	currentIndex := primitive.NilObjectID
 	totalDeleted := 0
+	startedAt := time.Now()
+
+	if state, ok, err := sr.load(d.ctx, d.runName); err != nil {
+		log.Error("failed to load checkpoint, starting from zero", "run", d.runName, "error", err)
+	} else if ok {
+		currentIndex = state.LastID
+		totalDeleted = state.TotalDeleted
+		startedAt = state.StartedAt
+		log.Info("resumed run", "run", d.runName, "index", currentIndex.Hex(), "total_deleted", totalDeleted)
+	} else if legacy, err := repo.NewCursorRepo(md).LoadCursor(d.ctx, "dedupe", ""); err == nil && legacy != primitive.NilObjectID {
+		// No _dupdel_state checkpoint yet, but a pre-upgrade binary left one
+		// under repo.CursorRepo's "dedupe" job. It's not migrated
+		// automatically since CursorRepo has no totalDeleted to carry over;
+		// surface it so an operator can pick it up with -run if needed.
+		log.Warn("found a legacy CursorRepo dedupe checkpoint; this run is starting from zero instead since the new _dupdel_state format has no checkpoint to migrate it into automatically", "legacy_index", legacy.Hex())
+	}
+
+	d.progress.init(d.runName, startedAt)
+	d.progress.update(currentIndex, totalDeleted)
+
+	log.Info("acquiring dupdel lock", "run", d.runName, "owner", d.owner)
+	for {
+		acquired, err := sr.tryAcquireLock(d.ctx, d.runName, d.owner, lockTTL)
+		if err != nil {
+			log.Error("failed to acquire dupdel lock, retrying", "run", d.runName, "error", err)
+		} else if acquired {
+			break
+		} else {
+			log.Info("run already locked by another instance, waiting", "run", d.runName)
+		}
+
+		select {
+		case <-d.ctx.Done():
+			log.Info("context cancelled while waiting for lock", "run", d.runName)
+			return
+		case <-time.After(lockRetryInterval):
+		}
+	}
+	log.Info("acquired dupdel lock", "run", d.runName, "owner", d.owner)
+
+	// stopRenew, not d.ctx, governs the renew goroutine's lifetime: Run can
+	// return on its own (scan completed) well before d.ctx is ever
+	// cancelled, and the renew goroutine must still wind down then so the
+	// deferred release below doesn't block forever waiting on renewDone.
+	stopRenew := make(chan struct{})
+	renewDone := make(chan struct{})
+	go func() {
+		defer close(renewDone)
+		ticker := time.NewTicker(lockRenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopRenew:
+				return
+			case <-ticker.C:
+				// tryAcquireLock also matches (and refreshes) a lock
+				// already held by owner, so it doubles as a renewal.
+				if _, err := sr.tryAcquireLock(d.ctx, d.runName, d.owner, lockTTL); err != nil {
+					log.Error("failed to renew dupdel lock", "run", d.runName, "error", err)
+				}
+			}
+		}
+	}()
+	defer func() {
+		close(stopRenew)
+		<-renewDone
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := sr.releaseLock(releaseCtx, d.runName, d.owner); err != nil {
+			log.Error("failed to release dupdel lock", "run", d.runName, "error", err)
+		}
+	}()
+
+	if adminAddr := os.Getenv("ADMIN_ADDR"); adminAddr != "" {
+		adminServer := admin.NewServer(os.Getenv("ADMIN_USERNAME"), os.Getenv("ADMIN_PASSWORD"))
+		adminServer.Handle("/api/dupdel/progress", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			admin.WriteJSON(w, d.Progress())
+		})
+
+		// /ws/events streams lifecycle events (currently just
+		// "duplicates_deleted") as they happen, the same LogRecord
+		// shape batchproxy's /ws/events uses.
+		adminServer.HandleWS("/ws/events", func(conn *websocket.Conn, r *http.Request) {
+			ch, unsubscribe := d.logBus.Subscribe()
+			defer unsubscribe()
+
+			admin.StreamLogRecords(conn, ch, nil)
+		})
+
+		go func() {
+			log.Info("Starting dupdel admin server", "addr", adminAddr)
+			if err := adminServer.ListenAndServe(adminAddr); err != nil && err != http.ErrServerClosed {
+				log.Error("Admin server stopped", "error", err)
+			}
+		}()
+		go func() {
+			<-d.ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			adminServer.Shutdown(shutdownCtx)
+		}()
+	}
+
+	// Run a bulk dedupe pass first: BulkDeduplicate groups duplicates
+	// across every app in one aggregation cursor and flushes deletes in
+	// batched BulkWrite calls, clearing out the bulk of a fresh
+	// collection's duplicates far faster than the per-user loop below
+	// ever could. That loop still runs afterward (and on every
+	// subsequent run) to catch duplicates introduced since this pass and
+	// to provide the resumable, checkpointed scan a crash can recover
+	// from. This pass's own deletions are checkpointed immediately below
+	// (instead of waiting for the loop's next cursorCheckpointInterval
+	// tick) so a crash right after it doesn't lose its count from the
+	// persisted run stats: a re-run after that would find those
+	// duplicates already gone and report 0 deleted here, undercounting
+	// total_deleted otherwise.
+	if apps, err := repo.NewAppRepo(md).GetAllApps(d.ctx); err != nil {
+		log.Error("bulk dedupe pre-pass: failed to load apps, skipping", "run", d.runName, "error", err)
+	} else if bulkResult, err := ur.BulkDeduplicate(d.ctx, apps, 0); err != nil {
+		log.Error("bulk dedupe pre-pass failed, falling back to per-user scan only", "run", d.runName, "error", err)
+	} else if bulkResult.DeletedCount > 0 {
+		totalDeleted += int(bulkResult.DeletedCount)
+		d.progress.update(currentIndex, totalDeleted)
+		if err := sr.checkpoint(d.ctx, d.runName, currentIndex, totalDeleted); err != nil {
+			log.Error("failed to checkpoint dupdel state after bulk pre-pass", "run", d.runName, "error", err)
+		}
+		log.Info("bulk dedupe pre-pass complete", "run", d.runName, "batches", bulkResult.Batches, "deleted", bulkResult.DeletedCount)
+		d.logBus.Publish(admin.LogRecord{
+			Timestamp: time.Now(),
+			Type:      "duplicates_deleted",
+			Message:   fmt.Sprintf("bulk pre-pass deleted %d duplicates across %d batches", bulkResult.DeletedCount, bulkResult.Batches),
+			Fields:    map[string]any{"count": bulkResult.DeletedCount, "total_deleted": totalDeleted, "batches": bulkResult.Batches},
+		})
+	} else {
+		log.Info("bulk dedupe pre-pass found no duplicates", "run", d.runName)
+	}
 
 	log.Info("Starting duplicate deletion process...")
 
+	checkedSinceCheckpoint := 0
+
 	for {
 		select {
 		case <-d.ctx.Done():
@@ -53,7 +302,10 @@ func (d *DupDel) Run() {
 			}
 
 			if user == nil {
-				log.Info("No more users found, duplicate deletion completed", "total_deleted", totalDeleted)
+				if err := sr.checkpoint(d.ctx, d.runName, currentIndex, totalDeleted); err != nil {
+					log.Error("failed to checkpoint dupdel state", "run", d.runName, "error", err)
+				}
+				log.Info("No more users found, duplicate deletion completed", "run", d.runName, "total_deleted", totalDeleted)
 				return
 			}
 
@@ -78,11 +330,26 @@ func (d *DupDel) Run() {
 				} else {
 					totalDeleted += len(duplicateIDs)
 					log.Info("Successfully deleted duplicates", "count", len(duplicateIDs), "total_deleted", totalDeleted)
+					d.logBus.Publish(admin.LogRecord{
+						Timestamp: time.Now(),
+						Type:      "duplicates_deleted",
+						Message:   fmt.Sprintf("deleted %d duplicates for user %s", len(duplicateIDs), user.ID.Hex()),
+						Fields:    map[string]any{"count": len(duplicateIDs), "total_deleted": totalDeleted},
+					})
 				}
 			}
 
 			// Update current index to this user's ID for next iteration
 			currentIndex = user.ID
+			checkedSinceCheckpoint++
+			d.progress.update(currentIndex, totalDeleted)
+
+			if checkedSinceCheckpoint >= cursorCheckpointInterval {
+				if err := sr.checkpoint(d.ctx, d.runName, currentIndex, totalDeleted); err != nil {
+					log.Error("failed to checkpoint dupdel state", "run", d.runName, "error", err)
+				}
+				checkedSinceCheckpoint = 0
+			}
 
 			// Small delay to prevent overwhelming the database
 			// time.Sleep(10 * time.Millisecond)
@@ -0,0 +1,129 @@
+package dupdel
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// stateCollectionName holds both checkpoint and lock documents for dupdel,
+// keyed by run name rather than the generic job_name repo.CursorRepo uses:
+// a run carries extra fields (totalDeleted, startedAt) repo.CursorRepo has
+// no room for, and needs its own lock document so two instances running
+// the same --run can't process overlapping ID ranges.
+const stateCollectionName = "_dupdel_state"
+
+// dupdelState is the checkpoint document for one run, _id stateDocID(runName).
+type dupdelState struct {
+	LastID       primitive.ObjectID `bson:"last_id"`
+	TotalDeleted int                `bson:"total_deleted"`
+	StartedAt    time.Time          `bson:"started_at"`
+	UpdatedAt    time.Time          `bson:"updated_at"`
+}
+
+// dupdelLock is the TTL lock document for one run, _id lockDocID(runName).
+// Expired or self-owned locks are stolen/renewed in place; a live lock held
+// by another owner makes the acquiring upsert hit the unique _id index and
+// fail with a duplicate-key error instead of matching.
+type dupdelLock struct {
+	Owner      string    `bson:"owner"`
+	AcquiredAt time.Time `bson:"acquired_at"`
+	ExpiresAt  time.Time `bson:"expires_at"`
+}
+
+func stateDocID(runName string) string { return "state:" + runName }
+func lockDocID(runName string) string  { return "lock:" + runName }
+
+type stateRepo struct {
+	mc *mongo.Collection
+}
+
+// newStateRepo returns a stateRepo over stateCollectionName, ensuring the
+// TTL index on expires_at exists so an instance that crashes without
+// releasing its lock doesn't block every future run forever.
+func newStateRepo(ctx context.Context, md *mongo.Database) (*stateRepo, error) {
+	mc := md.Collection(stateCollectionName)
+
+	_, err := mc.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"expires_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &stateRepo{mc: mc}, nil
+}
+
+// load returns the checkpoint for runName, or ok=false if none exists yet.
+func (r *stateRepo) load(ctx context.Context, runName string) (dupdelState, bool, error) {
+	var s dupdelState
+	err := r.mc.FindOne(ctx, bson.M{"_id": stateDocID(runName)}).Decode(&s)
+	if err == mongo.ErrNoDocuments {
+		return dupdelState{}, false, nil
+	}
+	if err != nil {
+		return dupdelState{}, false, err
+	}
+	return s, true, nil
+}
+
+// checkpoint upserts runName's progress. startedAt is only set on the
+// document's first insert, so it reflects when the run first began rather
+// than its latest checkpoint.
+func (r *stateRepo) checkpoint(ctx context.Context, runName string, lastID primitive.ObjectID, totalDeleted int) error {
+	now := time.Now()
+	_, err := r.mc.UpdateOne(ctx,
+		bson.M{"_id": stateDocID(runName)},
+		bson.M{
+			"$set":         bson.M{"last_id": lastID, "total_deleted": totalDeleted, "updated_at": now},
+			"$setOnInsert": bson.M{"started_at": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// reset discards runName's checkpoint, used by --restart to start a run
+// from primitive.NilObjectID regardless of what was checkpointed before.
+func (r *stateRepo) reset(ctx context.Context, runName string) error {
+	_, err := r.mc.DeleteOne(ctx, bson.M{"_id": stateDocID(runName)})
+	return err
+}
+
+// tryAcquireLock attempts to take or renew-in-place runName's lock for
+// owner, valid until ttl from now. It succeeds if no lock document exists,
+// the existing one has expired, or it's already held by owner; it reports
+// false (not an error) if another owner currently holds a live lock.
+func (r *stateRepo) tryAcquireLock(ctx context.Context, runName, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": lockDocID(runName),
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$lt": now}},
+			{"owner": owner},
+		},
+	}
+	update := bson.M{"$set": bson.M{"owner": owner, "acquired_at": now, "expires_at": now.Add(ttl)}}
+
+	_, err := r.mc.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// releaseLock drops runName's lock document, provided it's still held by
+// owner (a lock already stolen by a later owner, after this one's TTL
+// lapsed, must not be deleted out from under them).
+func (r *stateRepo) releaseLock(ctx context.Context, runName, owner string) error {
+	_, err := r.mc.DeleteOne(ctx, bson.M{"_id": lockDocID(runName), "owner": owner})
+	return err
+}
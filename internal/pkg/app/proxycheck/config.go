@@ -0,0 +1,69 @@
+package proxycheck
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-yaml"
+)
+
+// TestURL is one endpoint checkProxyBlocked probes per proxy. Weight
+// controls how much a single "blocked" verdict on it counts toward the
+// blocked/accessible quorum, so a flakier mirror can be given less say
+// than a primary site.
+type TestURL struct {
+	URL    string  `yaml:"url"`
+	Weight float64 `yaml:"weight"`
+}
+
+// CheckConfig tunes checkProxyBlocked: which URLs to probe per proxy (and
+// how heavily each one's verdict counts), and which response-body
+// substrings mark a page as blocked on top of a non-200 status.
+type CheckConfig struct {
+	TestURLs      []TestURL `yaml:"test_urls"`
+	ErrorPatterns []string  `yaml:"error_patterns"`
+}
+
+// DefaultCheckConfig is what NewProxyChecker uses unless overridden with
+// WithCheckConfig, matching checkProxyBlocked's URLs and error pattern
+// before this became configurable.
+func DefaultCheckConfig() CheckConfig {
+	return CheckConfig{
+		TestURLs: []TestURL{
+			{URL: "https://jktjkt48.com", Weight: 1},
+			{URL: "https://idrok5.com", Weight: 1},
+			{URL: "https://idrgamerp.com", Weight: 1},
+			{URL: "https://test.1gvdjbxcw.com", Weight: 1},
+		},
+		ErrorPatterns: []string{"errorOccurPath"},
+	}
+}
+
+// LoadCheckConfig reads a CheckConfig from a YAML file at path.
+func LoadCheckConfig(path string) (CheckConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return CheckConfig{}, fmt.Errorf("failed to read check config: %w", err)
+	}
+
+	var cfg CheckConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return CheckConfig{}, fmt.Errorf("failed to parse check config: %w", err)
+	}
+
+	if len(cfg.TestURLs) == 0 {
+		return CheckConfig{}, fmt.Errorf("check config must define at least one test_url")
+	}
+
+	// A test_url entry with no weight key unmarshals to the Go zero value
+	// (0), not "unweighted" - default it to 1 so an operator who omits
+	// weight entirely (the natural minimal config) gets equal-weight
+	// voting instead of a vote that never counts.
+	for i := range cfg.TestURLs {
+		if cfg.TestURLs[i].Weight == 0 {
+			cfg.TestURLs[i].Weight = 1
+		}
+	}
+
+	return cfg, nil
+}
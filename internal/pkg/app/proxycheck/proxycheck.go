@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"strings"
@@ -12,20 +13,93 @@ import (
 	"time"
 
 	"dayusch/internal/pkg/api/yarun"
+	"dayusch/internal/pkg/obs"
 
-	"github.com/charmbracelet/log"
 	"golang.org/x/sync/semaphore"
 )
 
+// perProxyURLConcurrency bounds how many of a single proxy's testURLs are
+// probed at once: checkProxyBlocked's own semaphore already bounds how
+// many proxies are checked concurrently, so this is a second, per-proxy
+// limit underneath it rather than a replacement for it.
+const perProxyURLConcurrency = 4
+
+// urlCheckMaxAttempts, urlCheckInitialBackoff, urlCheckMaxBackoff and
+// urlCheckJitter tune isURLBlockedWithRetry's retry of an unreachable
+// testURL: the same bit-shift-doubling-plus-jitter formula pragmatic's
+// RetryPolicy uses, reimplemented locally since proxycheck has no other
+// reason to depend on that package.
+const (
+	urlCheckMaxAttempts    = 3
+	urlCheckInitialBackoff = 200 * time.Millisecond
+	urlCheckMaxBackoff     = 2 * time.Second
+	urlCheckJitter         = 0.3
+)
+
 // ProxyChecker handles proxy checking operations
 type ProxyChecker struct {
 	yarunAPI      *yarun.YarunApi
 	limit         int
-	testURLs      []string
+	checkConfig   CheckConfig
 	httpClient    *http.Client
 	proxyUsername *string
 	proxyPassword *string
 	semaphore     *semaphore.Weighted
+	logger        *obs.Logger
+
+	stats stats
+}
+
+// Option configures a ProxyChecker at construction time.
+type Option func(*ProxyChecker)
+
+// WithCheckConfig overrides the URLs (and weights) checkProxyBlocked
+// probes per proxy and the body substrings it treats as a blocked page.
+// The default is DefaultCheckConfig().
+func WithCheckConfig(cfg CheckConfig) Option {
+	return func(pc *ProxyChecker) { pc.checkConfig = cfg }
+}
+
+// Stats is a point-in-time view of the most recent CheckProxies run,
+// returned by the admin server's /api/status.
+type Stats struct {
+	LastRunAt    time.Time `json:"last_run_at"`
+	Checked      int       `json:"checked"`
+	Unblocked    int       `json:"unblocked"`
+	StillBlocked int       `json:"still_blocked"`
+	Errors       int       `json:"errors"`
+}
+
+type stats struct {
+	mu sync.Mutex
+	Stats
+}
+
+func (s *stats) recordRunStart() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.LastRunAt = time.Now()
+	s.Checked, s.Unblocked, s.StillBlocked, s.Errors = 0, 0, 0, 0
+}
+
+func (s *stats) recordOutcome(outcome string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Checked++
+	switch outcome {
+	case "unblocked":
+		s.Unblocked++
+	case "still_blocked":
+		s.StillBlocked++
+	case "error":
+		s.Errors++
+	}
+}
+
+func (s *stats) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Stats
 }
 
 // IPifyResponse represents the response from ipify API
@@ -34,28 +108,43 @@ type IPifyResponse struct {
 }
 
 // NewProxyChecker creates a new proxy checker instance
-func NewProxyChecker(yarunURL, yarunToken, proxyUsername, proxyPassword string, limit int) *ProxyChecker {
-	return &ProxyChecker{
-		yarunAPI: yarun.NewYarunApi(yarunURL, yarunToken),
-		limit:    limit,
-		testURLs: []string{
-			"https://jktjkt48.com",
-			"https://idrok5.com",
-			"https://idrgamerp.com",
-			"https://test.1gvdjbxcw.com",
-		},
+func NewProxyChecker(yarunURL, yarunToken, proxyUsername, proxyPassword string, limit int, opts ...Option) *ProxyChecker {
+	pc := &ProxyChecker{
+		yarunAPI:    yarun.NewYarunApi(yarunURL, yarunToken),
+		limit:       limit,
+		checkConfig: DefaultCheckConfig(),
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 		proxyUsername: &proxyUsername,
 		proxyPassword: &proxyPassword,
 		semaphore:     semaphore.NewWeighted(10), // Allow up to 10 concurrent proxy checks
+		logger:        obs.Default(),
 	}
+
+	for _, opt := range opts {
+		opt(pc)
+	}
+
+	return pc
+}
+
+// Stop releases the checker's yarun client's auth backend (e.g. a file
+// watcher).
+func (pc *ProxyChecker) Stop() {
+	pc.yarunAPI.Stop()
+}
+
+// Stats returns a snapshot of the most recent CheckProxies run.
+func (pc *ProxyChecker) Stats() Stats {
+	return pc.stats.snapshot()
 }
 
 // CheckProxies performs the main proxy checking logic
 func (pc *ProxyChecker) CheckProxies(ctx context.Context) error {
-	log.Info("Fetching blocked proxies...")
+	ctx = obs.WithTraceID(ctx, obs.NewTraceID())
+	pc.stats.recordRunStart()
+	pc.logger.Info(ctx, "Fetching blocked proxies...")
 
 	// Check if context is already cancelled
 	select {
@@ -74,7 +163,7 @@ func (pc *ProxyChecker) CheckProxies(ctx context.Context) error {
 		return fmt.Errorf("API returned not ok for blocked proxies")
 	}
 
-	log.Info("Found blocked proxies to check", "count", len(blockedResp.Proxies))
+	pc.logger.Info(ctx, "Found blocked proxies to check", "count", len(blockedResp.Proxies))
 
 	var wg sync.WaitGroup
 
@@ -82,7 +171,7 @@ func (pc *ProxyChecker) CheckProxies(ctx context.Context) error {
 		// Check if context is cancelled before starting new goroutine
 		select {
 		case <-ctx.Done():
-			log.Info("Context cancelled, waiting for remaining goroutines to complete")
+			pc.logger.Info(ctx, "Context cancelled, waiting for remaining goroutines to complete")
 			wg.Wait()
 			return ctx.Err()
 		default:
@@ -90,7 +179,7 @@ func (pc *ProxyChecker) CheckProxies(ctx context.Context) error {
 
 		// Acquire semaphore permit
 		if err := pc.semaphore.Acquire(ctx, 1); err != nil {
-			log.Info("Context cancelled while acquiring semaphore, waiting for remaining goroutines to complete")
+			pc.logger.Info(ctx, "Context cancelled while acquiring semaphore, waiting for remaining goroutines to complete")
 			wg.Wait()
 			return ctx.Err()
 		}
@@ -101,7 +190,8 @@ func (pc *ProxyChecker) CheckProxies(ctx context.Context) error {
 			defer pc.semaphore.Release(1)
 
 			if err := pc.checkSingleProxy(ctx, proxy); err != nil {
-				log.Error("Error checking proxy", "id", proxy.ID, "port", proxy.Port, "error", err)
+				pc.logger.Error(ctx, "Error checking proxy", "id", proxy.ID, "port", proxy.Port, "error", err)
+				pc.stats.recordOutcome("error")
 			}
 		}(proxy)
 	}
@@ -114,12 +204,12 @@ func (pc *ProxyChecker) CheckProxies(ctx context.Context) error {
 
 // checkSingleProxy checks a single proxy for IP changes and accessibility
 func (pc *ProxyChecker) checkSingleProxy(ctx context.Context, proxy yarun.ProxyResponse) error {
-	log.Info("Checking proxy", "id", proxy.ID, "port", proxy.Port, "current_ip", proxy.IP)
+	pc.logger.Info(ctx, "Checking proxy", "id", proxy.ID, "port", proxy.Port, "current_ip", proxy.IP)
 
 	// Create proxy client
 	proxyURL := fmt.Sprintf("http://%s:%s@gw.dataimpulse.com:%d", *pc.proxyUsername, *pc.proxyPassword, proxy.Port)
 
-	// log.Info("proxy url", "url", proxyURL)
+	// pc.logger.Debug(ctx, "proxy url", "url", proxyURL)
 
 	proxyParsed, err := url.Parse(proxyURL)
 	if err != nil {
@@ -138,36 +228,48 @@ func (pc *ProxyChecker) checkSingleProxy(ctx context.Context, proxy yarun.ProxyR
 	// Check current IP
 	currentIP, err := pc.getCurrentIP(ctx, proxyClient)
 	if err != nil {
-		log.Error("Failed to get current IP for proxy", "id", proxy.ID, "port", proxy.Port, "error", err)
-		// If we can't get IP, assume it's still blocked and update last check
-		return pc.updateProxyLastCheck(ctx, proxy.ID, proxy.IP)
+		pc.logger.Error(ctx, "Failed to get current IP for proxy", "id", proxy.ID, "port", proxy.Port, "error", err)
+		// If we can't get IP, assume it's still blocked and update last check.
+		// Only record the outcome once we know updateProxyLastCheck succeeded;
+		// on failure CheckProxies' caller records "error" instead.
+		if err := pc.updateProxyLastCheck(ctx, proxy.ID, proxy.IP); err != nil {
+			return err
+		}
+		pc.stats.recordOutcome("still_blocked")
+		return nil
 	}
 
 	// If IP has changed, unblock the proxy
 	if currentIP != proxy.IP {
-		log.Info("Proxy IP changed, unblocking", "id", proxy.ID, "port", proxy.Port, "old_ip", proxy.IP, "new_ip", currentIP)
+		pc.logger.Info(ctx, "Proxy IP changed, unblocking", "id", proxy.ID, "port", proxy.Port, "old_ip", proxy.IP, "new_ip", currentIP)
 		_, err := pc.yarunAPI.UnblockProxy(ctx, proxy.ID, currentIP, false)
 		if err != nil {
 			return fmt.Errorf("failed to unblock proxy: %w", err)
 		}
-		log.Info("Successfully unblocked proxy with new IP", "id", proxy.ID, "port", proxy.Port, "new_ip", currentIP)
+		pc.logger.Info(ctx, "Successfully unblocked proxy with new IP", "id", proxy.ID, "port", proxy.Port, "new_ip", currentIP)
+		pc.stats.recordOutcome("unblocked")
 		return nil
 	}
 
 	// IP is the same, check if the proxy is accessible
 	isBlocked := pc.checkProxyBlocked(ctx, proxyClient)
 	if isBlocked {
-		log.Info("Proxy is still blocked, updating last check", "id", proxy.ID, "port", proxy.Port)
-		return pc.updateProxyLastCheck(ctx, proxy.ID, proxy.IP)
+		pc.logger.Info(ctx, "Proxy is still blocked, updating last check", "id", proxy.ID, "port", proxy.Port)
+		if err := pc.updateProxyLastCheck(ctx, proxy.ID, proxy.IP); err != nil {
+			return err
+		}
+		pc.stats.recordOutcome("still_blocked")
+		return nil
 	}
 
 	// Proxy is accessible, unblock it
-	log.Info("Proxy is now accessible, unblocking", "id", proxy.ID, "port", proxy.Port)
+	pc.logger.Info(ctx, "Proxy is now accessible, unblocking", "id", proxy.ID, "port", proxy.Port)
 	_, err = pc.yarunAPI.UnblockProxy(ctx, proxy.ID, proxy.IP, false)
 	if err != nil {
 		return fmt.Errorf("failed to unblock proxy: %w", err)
 	}
-	log.Info("Successfully unblocked accessible proxy", "id", proxy.ID, "port", proxy.Port)
+	pc.logger.Info(ctx, "Successfully unblocked accessible proxy", "id", proxy.ID, "port", proxy.Port)
+	pc.stats.recordOutcome("unblocked")
 	return nil
 }
 
@@ -201,51 +303,155 @@ func (pc *ProxyChecker) getCurrentIP(ctx context.Context, client *http.Client) (
 	return ipResp.IP, nil
 }
 
-// checkProxyBlocked checks if the proxy is blocked by testing URLs
+// urlVerdict is one testURL's outcome, gathered concurrently into a shared
+// slice guarded by checkProxyBlocked's mutex.
+type urlVerdict struct {
+	blocked bool
+	weight  float64
+}
+
+// checkProxyBlocked fans out pc.checkConfig.TestURLs concurrently (bounded
+// by a per-proxy sub-semaphore, independent of the semaphore in
+// CheckProxies that bounds how many proxies run at once) instead of
+// checking them one at a time and stopping at the first hit. A single
+// flaky mirror no longer decides the whole proxy: the verdict is a
+// weighted quorum across every URL that responded.
 func (pc *ProxyChecker) checkProxyBlocked(ctx context.Context, client *http.Client) bool {
-	for _, testURL := range pc.testURLs {
-		if pc.isURLBlocked(ctx, client, testURL) {
-			return true
+	urls := pc.checkConfig.TestURLs
+	if len(urls) == 0 {
+		return false
+	}
+
+	sem := semaphore.NewWeighted(perProxyURLConcurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	results := make([]urlVerdict, 0, len(urls))
+
+	for _, testURL := range urls {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			break // context cancelled; decide on whatever verdicts were gathered so far
+		}
+
+		wg.Add(1)
+		go func(testURL TestURL) {
+			defer wg.Done()
+			defer sem.Release(1)
+
+			blocked := pc.isURLBlockedWithRetry(ctx, client, testURL.URL)
+
+			mu.Lock()
+			results = append(results, urlVerdict{blocked: blocked, weight: testURL.Weight})
+			mu.Unlock()
+		}(testURL)
+	}
+
+	wg.Wait()
+
+	return quorumBlocked(results)
+}
+
+// quorumBlocked reports blocked only if the weight of URLs that reported
+// blocked is at least half of the total weight gathered. With every URL at
+// its default weight of 1, this is exactly "blocked count >= ceil(N/2)".
+func quorumBlocked(results []urlVerdict) bool {
+	var total, blocked float64
+	for _, r := range results {
+		total += r.weight
+		if r.blocked {
+			blocked += r.weight
 		}
 	}
-	return false
+	if total == 0 {
+		return false
+	}
+	return blocked*2 >= total
 }
 
-// isURLBlocked checks if a specific URL is blocked
-func (pc *ProxyChecker) isURLBlocked(ctx context.Context, client *http.Client, testURL string) bool {
+// isURLBlocked performs one fetch of testURL. err is non-nil only when
+// testURL couldn't be reached or a 200 response's body couldn't be read;
+// blocked reflects a completed
+// request's status code or body against pc.checkConfig.ErrorPatterns, and
+// is only meaningful when err is nil.
+func (pc *ProxyChecker) isURLBlocked(ctx context.Context, client *http.Client, testURL string) (bool, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", testURL, nil)
 	if err != nil {
-		log.Error("Failed to create request", "url", testURL, "error", err)
-		return false
+		return false, err
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Error("Failed to fetch URL", "url", testURL, "error", err)
-		return true // Assume blocked if we can't reach it
+		return false, err
 	}
 	defer resp.Body.Close()
 
 	// Check if status is not 200
 	if resp.StatusCode != http.StatusOK {
-		log.Warn("URL returned non-200 status, considering blocked", "url", testURL, "status", resp.StatusCode)
-		return true
+		pc.logger.Warn(ctx, "URL returned non-200 status, considering blocked", "url", testURL, "status", resp.StatusCode)
+		return true, nil
 	}
 
-	// Read response body to check for errorOccurPath
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Error("Failed to read response body", "url", testURL, "error", err)
-		return false
+		return false, err
 	}
 
 	bodyStr := string(body)
-	if strings.Contains(bodyStr, "errorOccurPath") {
-		log.Warn("URL contains errorOccurPath, blocked", "url", testURL)
-		return true
+	for _, pattern := range pc.checkConfig.ErrorPatterns {
+		if strings.Contains(bodyStr, pattern) {
+			pc.logger.Warn(ctx, "URL body matched a blocked pattern", "url", testURL, "pattern", pattern)
+			return true, nil
+		}
 	}
 
-	return false
+	return false, nil
+}
+
+// isURLBlockedWithRetry retries isURLBlocked while testURL is unreachable,
+// with jittered exponential backoff, up to urlCheckMaxAttempts. It never
+// retries a completed request's verdict, only a failure to reach the URL
+// at all. If it's still unreachable after every attempt, it's treated as
+// blocked, the same "assume blocked if we can't reach it" fallback the
+// single-attempt version used.
+func (pc *ProxyChecker) isURLBlockedWithRetry(ctx context.Context, client *http.Client, testURL string) bool {
+	var lastErr error
+	for attempt := 1; attempt <= urlCheckMaxAttempts; attempt++ {
+		blocked, err := pc.isURLBlocked(ctx, client, testURL)
+		if err == nil {
+			return blocked
+		}
+		lastErr = err
+
+		if attempt == urlCheckMaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			pc.logger.Error(ctx, "Context cancelled while retrying URL check, assuming blocked", "url", testURL)
+			return true
+		case <-time.After(urlCheckBackoff(attempt)):
+		}
+	}
+
+	pc.logger.Error(ctx, "Failed to fetch URL after retries, assuming blocked", "url", testURL, "error", lastErr)
+	return true
+}
+
+// urlCheckBackoff returns the delay before retrying attempt's URL fetch:
+// urlCheckInitialBackoff doubled per attempt up to urlCheckMaxBackoff,
+// jittered by +/-urlCheckJitter so concurrently-retrying URLs for the same
+// proxy don't all retry in lockstep.
+func urlCheckBackoff(attempt int) time.Duration {
+	d := urlCheckInitialBackoff << (attempt - 1)
+	if d <= 0 || d > urlCheckMaxBackoff {
+		d = urlCheckMaxBackoff
+	}
+
+	delta := time.Duration(float64(d) * urlCheckJitter)
+	if delta <= 0 {
+		return d
+	}
+	return d - delta/2 + time.Duration(rand.Int63n(int64(delta)))
 }
 
 // updateProxyLastCheck updates the proxy's last check time while keeping it blocked
@@ -0,0 +1,87 @@
+package statsworker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"dayusch/internal/pkg/repo"
+)
+
+// ringSize matches the "10 most-recent" lists AggregateAppStats computes
+// with $sort+$limit per bucket.
+const ringSize = 10
+
+// ringEntry is one user tracked in a ring, identified by ID so a later
+// reclassification can find and remove it.
+type ringEntry struct {
+	ID          primitive.ObjectID
+	Username    string
+	Balance     float64
+	Coin        float64
+	LastCheckAt time.Time
+}
+
+// ring keeps the ringSize most-recently-checked users for one AppStats
+// bucket (latest/playable/jackpot). It's small enough that a linear
+// re-sort on every insert is cheaper than a heap.
+type ring struct {
+	mu      sync.Mutex
+	entries []ringEntry
+}
+
+func newRing() *ring {
+	return &ring{}
+}
+
+// upsert inserts or updates e, keeping entries sorted by LastCheckAt
+// descending and capped at ringSize.
+func (r *ring) upsert(e ringEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removeLocked(e.ID)
+	r.entries = append(r.entries, e)
+	sort.Slice(r.entries, func(i, j int) bool {
+		return r.entries[i].LastCheckAt.After(r.entries[j].LastCheckAt)
+	})
+	if len(r.entries) > ringSize {
+		r.entries = r.entries[:ringSize]
+	}
+}
+
+// remove drops id from the ring if present - used when a user is
+// reclassified out of this bucket.
+func (r *ring) remove(id primitive.ObjectID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removeLocked(id)
+}
+
+func (r *ring) removeLocked(id primitive.ObjectID) {
+	for i, e := range r.entries {
+		if e.ID == id {
+			r.entries = append(r.entries[:i], r.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// list returns the ring's entries as repo.RecentUser, ready to flush.
+func (r *ring) list() []repo.RecentUser {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]repo.RecentUser, len(r.entries))
+	for i, e := range r.entries {
+		out[i] = repo.RecentUser{
+			Username:    e.Username,
+			Balance:     e.Balance,
+			Coin:        e.Coin,
+			LastCheckAt: e.LastCheckAt,
+		}
+	}
+	return out
+}
@@ -0,0 +1,323 @@
+package statsworker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"dayusch/internal/pkg/repo"
+)
+
+// jackpotBalance mirrors the $gte 100000 threshold AggregateAppStats uses
+// for jackpot_users_count/_list.
+const jackpotBalance = 100000
+
+// userClass is the bucket membership statsworker last observed for one
+// user, so the next change event can be diffed against it instead of
+// re-deriving "what changed" from scratch.
+type userClass struct {
+	AppCode        string
+	Valid          bool
+	Playable       bool
+	Jackpot        bool
+	ProcessedToday bool
+}
+
+// appState is the in-memory counters and recency rings for one app_code,
+// flushed to AppStats periodically by StatsWorker.
+type appState struct {
+	mu sync.Mutex
+
+	validCount     int
+	playableCount  int
+	jackpotCount   int
+	processedCount int
+
+	latest   *ring
+	playable *ring
+	jackpot  *ring
+}
+
+func newAppState() *appState {
+	return &appState{
+		latest:   newRing(),
+		playable: newRing(),
+		jackpot:  newRing(),
+	}
+}
+
+func (s *appState) counts() repo.AppStatsCounts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return repo.AppStatsCounts{
+		ValidUsersCount:      s.validCount,
+		PlayableUsersCount:   s.playableCount,
+		JackpotUsersCount:    s.jackpotCount,
+		ProcessedUsersCount:  s.processedCount,
+		LatestUsersCheckList: s.latest.list(),
+		PlayableUsersList:    s.playable.list(),
+		JackpotUsersList:     s.jackpot.list(),
+	}
+}
+
+// resetDaily zeroes the "since midnight" counter at the GMT+7 day
+// boundary; the other counters are cumulative snapshots, not per-day.
+func (s *appState) resetDaily() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.processedCount = 0
+}
+
+// state is statsworker's whole in-memory picture: per-app counters/rings
+// plus the per-user classification cache that makes incremental deltas
+// possible (a change event only tells us the new document, not which
+// buckets the user used to be in).
+type state struct {
+	mu      sync.RWMutex
+	configs map[string]repo.AppConfig // app_code -> balance range
+	apps    map[string]*appState      // app_code -> counters/rings
+	classes map[primitive.ObjectID]userClass
+}
+
+func newState() *state {
+	return &state{
+		configs: make(map[string]repo.AppConfig),
+		apps:    make(map[string]*appState),
+		classes: make(map[primitive.ObjectID]userClass),
+	}
+}
+
+func (s *state) appStateFor(appCode string) *appState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	as, ok := s.apps[appCode]
+	if !ok {
+		as = newAppState()
+		s.apps[appCode] = as
+	}
+	return as
+}
+
+func (s *state) loadConfigs(configs []repo.AppConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range configs {
+		s.configs[c.AppCode] = c
+	}
+}
+
+func (s *state) configFor(appCode string) (repo.AppConfig, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.configs[appCode]
+	return c, ok
+}
+
+// seedUser is the projection seed scans over the users collection
+// decode into, to build both the initial counters/rings and the
+// classification cache in a single pass.
+type seedUser struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	AppCode       string             `bson:"app_code"`
+	Username      string             `bson:"username"`
+	IsInvalidCred bool               `bson:"is_invalid_cred"`
+	Balance       float64            `bson:"balance"`
+	Coin          float64            `bson:"coin"`
+	LastCheckAt   time.Time          `bson:"last_check_at"`
+}
+
+// classify derives u's bucket membership from cfg and dayStart, the same
+// rules AggregateAppStats' $facet pipeline applies per app.
+func classify(u seedUser, cfg repo.AppConfig, dayStart time.Time) userClass {
+	valid := !u.IsInvalidCred
+	hasCheck := !u.LastCheckAt.IsZero()
+
+	playable := valid && hasCheck && u.Balance >= cfg.GameMinBalance && u.Balance <= cfg.GameMaxBalance
+	jackpot := valid && hasCheck && u.Balance >= jackpotBalance
+	processedToday := valid && (u.LastCheckAt.Equal(dayStart) || u.LastCheckAt.After(dayStart))
+
+	return userClass{
+		AppCode:        u.AppCode,
+		Valid:          valid,
+		Playable:       playable,
+		Jackpot:        jackpot,
+		ProcessedToday: processedToday,
+	}
+}
+
+// seed does a single linear scan of the users collection, building the
+// classification cache and counters/rings from scratch. It's the
+// once-at-startup replacement for re-deriving bucket membership from each
+// change event's predecessor, and is the only place that still looks at
+// every user - after this it's all deltas.
+func (s *state) seed(ctx context.Context, userColl *mongo.Collection, dayStart time.Time) error {
+	projection := bson.M{
+		"_id": 1, "app_code": 1, "username": 1,
+		"is_invalid_cred": 1, "balance": 1, "coin": 1, "last_check_at": 1,
+	}
+
+	cur, err := userColl.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var u seedUser
+		if err := cur.Decode(&u); err != nil {
+			return err
+		}
+
+		cfg, _ := s.configFor(u.AppCode)
+		class := classify(u, cfg, dayStart)
+
+		s.mu.Lock()
+		s.classes[u.ID] = class
+		s.mu.Unlock()
+
+		s.applyClass(u, class, true)
+	}
+	return cur.Err()
+}
+
+// applyClass folds one user's current classification into its app's
+// counters/rings. adding is true for a brand-new member (seed, or a user
+// that just started counting), false is handled by applyDelta below via
+// the explicit -1/+1 pair so a reclassification never double counts.
+func (s *state) applyClass(u seedUser, class userClass, adding bool) {
+	as := s.appStateFor(u.AppCode)
+	entry := ringEntry{ID: u.ID, Username: u.Username, Balance: u.Balance, Coin: u.Coin, LastCheckAt: u.LastCheckAt}
+
+	as.mu.Lock()
+	if adding {
+		if class.Valid {
+			as.validCount++
+		}
+		if class.Playable {
+			as.playableCount++
+		}
+		if class.Jackpot {
+			as.jackpotCount++
+		}
+		if class.ProcessedToday {
+			as.processedCount++
+		}
+	}
+	as.mu.Unlock()
+
+	if !u.LastCheckAt.IsZero() {
+		as.latest.upsert(entry)
+	}
+	if class.Playable {
+		as.playable.upsert(entry)
+	} else {
+		as.playable.remove(u.ID)
+	}
+	if class.Jackpot {
+		as.jackpot.upsert(entry)
+	} else {
+		as.jackpot.remove(u.ID)
+	}
+}
+
+// applyDelta reclassifies a user after a change event: it diffs the new
+// classification against whatever was cached for that user (or a
+// zero-value "not seen before" class) and adjusts counters by the
+// difference, so a user crossing into/out of a bucket moves exactly one
+// count each way instead of the whole bucket being recomputed.
+func (s *state) applyDelta(u seedUser, newClass userClass) {
+	s.mu.Lock()
+	oldClass, known := s.classes[u.ID]
+	s.classes[u.ID] = newClass
+	s.mu.Unlock()
+
+	as := s.appStateFor(u.AppCode)
+	entry := ringEntry{ID: u.ID, Username: u.Username, Balance: u.Balance, Coin: u.Coin, LastCheckAt: u.LastCheckAt}
+
+	as.mu.Lock()
+	if !known {
+		oldClass = userClass{}
+	}
+	if newClass.Valid != oldClass.Valid {
+		if newClass.Valid {
+			as.validCount++
+		} else {
+			as.validCount--
+		}
+	}
+	if newClass.Playable != oldClass.Playable {
+		if newClass.Playable {
+			as.playableCount++
+		} else {
+			as.playableCount--
+		}
+	}
+	if newClass.Jackpot != oldClass.Jackpot {
+		if newClass.Jackpot {
+			as.jackpotCount++
+		} else {
+			as.jackpotCount--
+		}
+	}
+	if newClass.ProcessedToday && !oldClass.ProcessedToday {
+		as.processedCount++
+	}
+	as.mu.Unlock()
+
+	if !u.LastCheckAt.IsZero() {
+		as.latest.upsert(entry)
+	}
+	if newClass.Playable {
+		as.playable.upsert(entry)
+	} else {
+		as.playable.remove(u.ID)
+	}
+	if newClass.Jackpot {
+		as.jackpot.upsert(entry)
+	} else {
+		as.jackpot.remove(u.ID)
+	}
+}
+
+// resetDaily zeroes every app's processed-today counter at the GMT+7
+// boundary, ahead of AggregateAppStats' own daily reseed reconciling the
+// exact value.
+func (s *state) resetDaily() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, as := range s.apps {
+		as.resetDaily()
+	}
+}
+
+// resyncCounts overwrites appCode's cumulative counters with freshly
+// reconciled values read back from AppStats, after a daily
+// AggregateAppStats reseed. Rings are left alone - they're a live
+// "most recent" view, not part of drift reconciliation.
+func (s *state) resyncCounts(appCode string, counts repo.AppStatsCounts) {
+	as := s.appStateFor(appCode)
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.validCount = counts.ValidUsersCount
+	as.playableCount = counts.PlayableUsersCount
+	as.jackpotCount = counts.JackpotUsersCount
+	as.processedCount = counts.ProcessedUsersCount
+}
+
+func (s *state) appCodes() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	codes := make([]string, 0, len(s.apps))
+	for code := range s.apps {
+		codes = append(codes, code)
+	}
+	return codes
+}
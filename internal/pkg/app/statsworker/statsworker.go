@@ -0,0 +1,265 @@
+// Package statsworker maintains the AppStats collection incrementally, as
+// a lighter-weight alternative to schstat's periodic
+// AggregateAppStats $lookup/$facet/$merge pipeline: it seeds its counters
+// with one full scan (and one AggregateAppStats call, to reconcile
+// first_*/inc_* the aggregation owns), then keeps them current off a
+// MongoDB change stream instead of rescanning the users collection
+// every cycle.
+package statsworker
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"dayusch/internal/pkg/db"
+	"dayusch/internal/pkg/obs"
+	"dayusch/internal/pkg/repo"
+)
+
+// jobName identifies this worker's change stream checkpoint in
+// repo.StatsRepo, the same way batch.cursorJobClaim names BatchApp's
+// cursor.
+const jobName = "statsworker"
+
+// flushInterval caps how often in-memory counters hit Mongo; a change
+// stream can deliver many events per second under load, but AppStats
+// doesn't need sub-second freshness.
+const flushInterval = 5 * time.Second
+
+// resumeTokenInterval caps how often the change stream's resume token is
+// persisted, independent of flushInterval since losing a few seconds of
+// token progress just means re-applying already-idempotent deltas.
+const resumeTokenInterval = 10 * time.Second
+
+type StatsWorker struct {
+	ctx    context.Context
+	logger *obs.Logger
+}
+
+func NewStatsWorker(ctx context.Context) *StatsWorker {
+	return &StatsWorker{
+		ctx:    ctx,
+		logger: obs.Default(),
+	}
+}
+
+func (a *StatsWorker) Run() {
+	uri := os.Getenv("MONGO_URI")
+	dbName := os.Getenv("MONGO_DB")
+
+	md, err := db.NewDbCon(a.ctx, uri, dbName)
+	if err != nil {
+		a.logger.Fatal(a.ctx, "failed to connect to mongo", "error", err)
+	}
+
+	ar := repo.NewAppRepo(md)
+	sr := repo.NewStatsRepo(md)
+	userColl := md.Collection(repo.CollectionUsers)
+
+	st := newState()
+
+	if err := a.reseed(a.ctx, ar, sr, st, userColl); err != nil {
+		a.logger.Fatal(a.ctx, "failed initial aggregate/seed", "error", err)
+	}
+
+	go a.dailyReseedLoop(ar, sr, st)
+	go a.flushLoop(sr, st)
+
+	a.watch(userColl, sr, st)
+}
+
+// reseed runs AggregateAppStats to let the existing pipeline own
+// first_*/inc_* and reconcile any drift, then reloads app configs and
+// rescans users to rebuild the in-memory classification cache and
+// counters from scratch.
+func (a *StatsWorker) reseed(ctx context.Context, ar *repo.AppRepo, sr *repo.StatsRepo, st *state, userColl *mongo.Collection) error {
+	if err := ar.AggregateAppStats(ctx); err != nil {
+		return err
+	}
+
+	configs, err := ar.ListAppConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	st.loadConfigs(configs)
+
+	return st.seed(ctx, userColl, dayStartGMT7(time.Now()))
+}
+
+// dayStartGMT7 returns today's 00:01:00 boundary in GMT+7, the exact
+// cutoff AggregateAppStats uses for processed_users_count, so the
+// in-memory classification agrees with what the aggregation just wrote.
+func dayStartGMT7(now time.Time) time.Time {
+	n := now.In(time.FixedZone("GMT+7", 7*60*60))
+	return time.Date(n.Year(), n.Month(), n.Day(), 0, 1, 0, 0, n.Location())
+}
+
+// dailyReseedLoop re-runs AggregateAppStats once a day at the GMT+7
+// boundary, then resyncs in-memory counters to whatever it reconciled -
+// the same role schstat's loop plays, just once a day instead of every
+// 15s, since the change stream keeps counters current in between.
+func (a *StatsWorker) dailyReseedLoop(ar *repo.AppRepo, sr *repo.StatsRepo, st *state) {
+	for {
+		now := time.Now()
+		next := dayStartGMT7(now).AddDate(0, 0, 1)
+
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		ctx := obs.WithTraceID(a.ctx, obs.NewTraceID())
+		st.resetDaily()
+
+		if err := ar.AggregateAppStats(ctx); err != nil {
+			a.logger.Error(ctx, "daily reaggregate failed", "error", err)
+			continue
+		}
+
+		dateStr := dayStartGMT7(time.Now()).Format("02-01-2006")
+		for _, appCode := range st.appCodes() {
+			counts, err := sr.GetAppStatsCounts(ctx, appCode, dateStr)
+			if err != nil {
+				a.logger.Error(ctx, "failed to read back reconciled counts", "app_code", appCode, "error", err)
+				continue
+			}
+			st.resyncCounts(appCode, counts)
+		}
+		a.logger.Info(ctx, "daily reaggregate and resync done")
+	}
+}
+
+// flushLoop periodically writes every app's in-memory counters/rings to
+// AppStats, so a crash between events loses at most flushInterval of
+// updates rather than the whole in-memory state.
+func (a *StatsWorker) flushLoop(sr *repo.StatsRepo, st *state) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			a.flushAll(sr, st)
+		}
+	}
+}
+
+func (a *StatsWorker) flushAll(sr *repo.StatsRepo, st *state) {
+	ctx := obs.WithTraceID(a.ctx, obs.NewTraceID())
+	dateStr := dayStartGMT7(time.Now()).Format("02-01-2006")
+
+	for _, appCode := range st.appCodes() {
+		as := st.appStateFor(appCode)
+		if err := sr.FlushAppStats(ctx, appCode, dateStr, as.counts()); err != nil {
+			a.logger.Error(ctx, "failed to flush app stats", "app_code", appCode, "error", err)
+		}
+	}
+}
+
+// watch opens the change stream on userColl and applies every matching
+// update as a delta, reconnecting with the last persisted resume token
+// on transient errors instead of restarting from a full reseed.
+func (a *StatsWorker) watch(userColl *mongo.Collection, sr *repo.StatsRepo, st *state) {
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		default:
+		}
+
+		ctx := obs.WithTraceID(a.ctx, obs.NewTraceID())
+
+		token, err := sr.LoadResumeToken(ctx, jobName)
+		if err != nil {
+			a.logger.Error(ctx, "failed to load resume token, watching from now", "error", err)
+		}
+
+		// Only insert/replace (a brand-new or fully-replaced doc, which
+		// could affect any bucket) or an update that actually touched one
+		// of the fields bucket membership depends on - a change to, say,
+		// username shouldn't wake up every bucket recompute.
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{
+				"$or": bson.A{
+					bson.M{"operationType": "insert"},
+					bson.M{"operationType": "replace"},
+					bson.M{
+						"operationType": "update",
+						"$or": bson.A{
+							bson.M{"updateDescription.updatedFields.balance": bson.M{"$exists": true}},
+							bson.M{"updateDescription.updatedFields.coin": bson.M{"$exists": true}},
+							bson.M{"updateDescription.updatedFields.is_invalid_cred": bson.M{"$exists": true}},
+							bson.M{"updateDescription.updatedFields.last_check_at": bson.M{"$exists": true}},
+						},
+					},
+				},
+			}}},
+		}
+
+		streamOpts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+		if token != nil {
+			streamOpts.SetResumeAfter(token)
+		}
+
+		stream, err := userColl.Watch(ctx, pipeline, streamOpts)
+		if err != nil {
+			a.logger.Error(ctx, "failed to open change stream, retrying", "error", err)
+			a.watchBackoff(ctx)
+			continue
+		}
+
+		a.consume(ctx, stream, sr, st)
+		stream.Close(ctx)
+	}
+}
+
+// consume drains stream until it errors out or ctx is cancelled,
+// applying each event's delta and periodically persisting the resume
+// token.
+func (a *StatsWorker) consume(ctx context.Context, stream *mongo.ChangeStream, sr *repo.StatsRepo, st *state) {
+	lastTokenSave := time.Now()
+
+	for stream.Next(ctx) {
+		var event struct {
+			FullDocument seedUser `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&event); err != nil {
+			a.logger.Error(ctx, "failed to decode change event", "error", err)
+			continue
+		}
+
+		dayStart := dayStartGMT7(time.Now())
+		cfg, _ := st.configFor(event.FullDocument.AppCode)
+		st.applyDelta(event.FullDocument, classify(event.FullDocument, cfg, dayStart))
+
+		if time.Since(lastTokenSave) >= resumeTokenInterval {
+			if err := sr.SaveResumeToken(ctx, jobName, stream.ResumeToken()); err != nil {
+				a.logger.Error(ctx, "failed to save resume token", "error", err)
+			}
+			lastTokenSave = time.Now()
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		a.logger.Error(ctx, "change stream ended with error, reconnecting", "error", err)
+	}
+	if err := sr.SaveResumeToken(ctx, jobName, stream.ResumeToken()); err != nil {
+		a.logger.Error(ctx, "failed to save resume token on close", "error", err)
+	}
+}
+
+func (a *StatsWorker) watchBackoff(ctx context.Context) {
+	const delay = 5 * time.Second
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+}
@@ -0,0 +1,76 @@
+// Package auth provides pluggable credential backends for the yarun and
+// CFBatch API clients, replacing the bare token strings those clients
+// used to take directly. Descriptors are URL-style: "static://token=...",
+// "htpasswd://file=...", "vault://path=...", "env://VAR". A descriptor
+// with no "scheme://" prefix is treated as a literal static token, so
+// existing callers that just pass a token string keep working unchanged.
+// File-based backends watch their source and hot-reload in the
+// background, so a credential rotation on disk takes effect without
+// restarting batchproxy.Run or proxycheck.NewProxyChecker.
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Auth supplies the credential header value for outgoing API requests.
+type Auth interface {
+	// Header returns the current header value, re-evaluated on every
+	// call so a hot-reloaded credential takes effect on the next request.
+	Header(ctx context.Context) (string, error)
+	// Stop releases any background resources (file watchers) the backend
+	// holds. Safe to call on a backend that has none.
+	Stop()
+}
+
+// New parses descriptor and returns the matching Auth backend. A
+// descriptor with no "scheme://" prefix is a literal static token.
+func New(descriptor string) (Auth, error) {
+	scheme, rest, ok := strings.Cut(descriptor, "://")
+	if !ok {
+		return newStaticAuth(descriptor)
+	}
+
+	switch scheme {
+	case "static":
+		return newStaticAuth(parseParams(rest)["token"])
+	case "htpasswd":
+		path := parseParams(rest)["file"]
+		if path == "" {
+			return nil, fmt.Errorf("auth: htpasswd:// descriptor requires file=...")
+		}
+		return newFileAuth(path)
+	case "vault":
+		path := parseParams(rest)["path"]
+		if path == "" {
+			return nil, fmt.Errorf("auth: vault:// descriptor requires path=...")
+		}
+		// No Vault client is vendored here; this assumes a vault-agent
+		// sidecar renders the secret to a local file at path, the same
+		// "read a file, watch it for changes" contract htpasswd:// uses.
+		return newFileAuth(path)
+	case "env":
+		if rest == "" {
+			return nil, fmt.Errorf("auth: env:// descriptor requires a variable name")
+		}
+		return newEnvAuth(rest)
+	default:
+		return nil, fmt.Errorf("auth: unknown scheme %q", scheme)
+	}
+}
+
+// parseParams parses a "key=value,key2=value2" rest segment used by the
+// static/htpasswd/vault descriptors.
+func parseParams(rest string) map[string]string {
+	params := make(map[string]string)
+	for _, pair := range strings.Split(rest, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		params[k] = v
+	}
+	return params
+}
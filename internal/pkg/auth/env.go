@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// envAuth reads its token from an environment variable on every call,
+// which keeps Header's "current value" contract even though nothing
+// here actively watches the environment for changes.
+type envAuth struct {
+	varName string
+}
+
+func newEnvAuth(varName string) (Auth, error) {
+	return &envAuth{varName: varName}, nil
+}
+
+func (a *envAuth) Header(ctx context.Context) (string, error) {
+	v := os.Getenv(a.varName)
+	if v == "" {
+		return "", fmt.Errorf("auth: env var %s is not set", a.varName)
+	}
+	return v, nil
+}
+
+func (a *envAuth) Stop() {}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// filePollInterval caps how often a file-backed backend re-stats its
+// source to check for a rotated credential.
+const filePollInterval = 5 * time.Second
+
+// fileAuth reads a token from the first line of a file, re-reading it
+// whenever the file's mtime changes. Used by both htpasswd:// (a local
+// credentials file) and vault:// (assumed to be kept current by a
+// vault-agent sidecar) - both are "a file on disk holds the current
+// token" in the end.
+type fileAuth struct {
+	path string
+
+	mu      sync.RWMutex
+	token   string
+	modTime time.Time
+	lastErr error
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func newFileAuth(path string) (Auth, error) {
+	a := &fileAuth{path: path, stopCh: make(chan struct{})}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+
+	go a.watch()
+	return a, nil
+}
+
+func (a *fileAuth) reload() error {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return fmt.Errorf("auth: failed to stat %s: %w", a.path, err)
+	}
+
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return fmt.Errorf("auth: failed to read %s: %w", a.path, err)
+	}
+
+	token := strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0])
+	if token == "" {
+		return fmt.Errorf("auth: %s is empty", a.path)
+	}
+
+	a.mu.Lock()
+	a.token = token
+	a.modTime = info.ModTime()
+	a.lastErr = nil
+	a.mu.Unlock()
+	return nil
+}
+
+// watch polls for mtime changes instead of an OS-level file watcher,
+// matching the repo's preference for plain polling loops (schstat,
+// BatchApp's round loop) over pulling in a new watcher dependency for
+// what's a low-frequency event. A reload that fails (e.g. a
+// write-in-progress partial file) keeps serving the last good token
+// rather than failing every request.
+func (a *fileAuth) watch() {
+	ticker := time.NewTicker(filePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stopCh:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(a.path)
+			if err != nil {
+				continue
+			}
+
+			a.mu.RLock()
+			unchanged := info.ModTime().Equal(a.modTime)
+			a.mu.RUnlock()
+			if unchanged {
+				continue
+			}
+
+			if err := a.reload(); err != nil {
+				a.mu.Lock()
+				a.lastErr = err
+				a.mu.Unlock()
+			}
+		}
+	}
+}
+
+func (a *fileAuth) Header(ctx context.Context) (string, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.token == "" {
+		return "", a.lastErr
+	}
+	return a.token, nil
+}
+
+func (a *fileAuth) Stop() {
+	a.stopOnce.Do(func() { close(a.stopCh) })
+}
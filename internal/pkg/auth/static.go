@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+)
+
+// staticAuth always returns the token it was constructed with.
+type staticAuth struct {
+	token string
+}
+
+func newStaticAuth(token string) (Auth, error) {
+	if token == "" {
+		return nil, fmt.Errorf("auth: static token is empty")
+	}
+	return &staticAuth{token: token}, nil
+}
+
+func (a *staticAuth) Header(ctx context.Context) (string, error) {
+	return a.token, nil
+}
+
+func (a *staticAuth) Stop() {}
+
+// failingAuth always returns the error it was constructed with. It lets a
+// constructor with no error return (NewYarunApi, NewCFBatchApi) fail every
+// request with a clear "bad descriptor" error instead of panicking or
+// silently sending no credentials at all.
+type failingAuth struct {
+	err error
+}
+
+// Failing returns an Auth whose Header always fails with err.
+func Failing(err error) Auth {
+	return &failingAuth{err: err}
+}
+
+func (a *failingAuth) Header(ctx context.Context) (string, error) {
+	return "", a.err
+}
+
+func (a *failingAuth) Stop() {}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"dayusch/internal/pkg/app/batch"
+)
+
+// BatchCommand runs the CFBatch claim-processing loop over WireGuard
+// egress (internal/pkg/app/batch). The app currently reads its
+// configuration straight from the environment, so the flag values are
+// mirrored into it via os.Setenv rather than threading them through a new
+// constructor signature.
+func BatchCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "batch",
+		Usage: "Run the CFBatch claim-processing loop over WireGuard egress",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "cfbatch-url", EnvVars: []string{"DAYU_CFBATCH_URL", "CF_BATCH_URL"}, Usage: "CFBatch API base URL"},
+			&cli.StringFlag{Name: "cfbatch-token", EnvVars: []string{"DAYU_CFBATCH_TOKEN", "CF_BATCH_TOKEN"}, Usage: "CFBatch API token"},
+			&cli.StringFlag{Name: "wg-privatekey", EnvVars: []string{"DAYU_WG_PRIVATEKEY", "WG_PRIVATEKEY"}, Usage: "WireGuard private key"},
+			&cli.StringFlag{Name: "wg-endpoint", EnvVars: []string{"DAYU_WG_ENDPOINT", "WG_ENDPOINT"}, Usage: "WireGuard endpoint (host:port)"},
+		},
+		Before: ApplyLogLevel,
+		Action: func(c *cli.Context) error {
+			ctx, cancel := WithShutdown(c.Context)
+			defer cancel()
+
+			os.Setenv("MONGO_URI", c.String("mongo-uri"))
+			os.Setenv("MONGO_DB", c.String("mongo-db"))
+			os.Setenv("CF_BATCH_URL", c.String("cfbatch-url"))
+			os.Setenv("CF_BATCH_TOKEN", c.String("cfbatch-token"))
+			os.Setenv("WG_PRIVATEKEY", c.String("wg-privatekey"))
+			os.Setenv("WG_ENDPOINT", c.String("wg-endpoint"))
+
+			batch.NewBatchApp(ctx).Run()
+			return nil
+		},
+	}
+}
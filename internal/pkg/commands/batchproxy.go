@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"github.com/urfave/cli/v2"
+
+	"dayusch/internal/pkg/app/batchproxy"
+)
+
+// BatchProxyCommand runs the batch-claim worker pool
+// (internal/pkg/app/batchproxy) against a pool of proxies from yarun.
+func BatchProxyCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "batchproxy",
+		Usage: "Run batch claims through a rotating pool of yarun proxies",
+		Flags: []cli.Flag{
+			&cli.UintFlag{Name: "concurrent", Aliases: []string{"c"}, Value: 10, Usage: "Number of concurrent workers"},
+			&cli.UintFlag{Name: "batch", Aliases: []string{"b"}, Value: 10, Usage: "Batch limit"},
+			&cli.UintFlag{Name: "delay", Aliases: []string{"d"}, Value: 0, Usage: "Delay between rounds in seconds"},
+			&cli.StringFlag{Name: "input", Aliases: []string{"i"}, Usage: "Input YAML config file"},
+			&cli.BoolFlag{Name: "no-progress", Usage: "Disable the live progress bar"},
+		},
+		Before: ApplyLogLevel,
+		Action: func(c *cli.Context) error {
+			batchproxy.Run(
+				c.Uint("concurrent"),
+				c.Uint("batch"),
+				c.Uint("delay"),
+				c.String("input"),
+				c.Bool("no-progress"),
+			)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"dayusch/internal/pkg/app/checker"
+)
+
+// retryPolicyFromFlags builds a checker.RetryPolicy from CLI flags, or nil
+// if retries weren't requested (--max-attempts <= 1, the default).
+func retryPolicyFromFlags(c *cli.Context) *checker.RetryPolicy {
+	if c.Int("max-attempts") <= 1 {
+		return nil
+	}
+
+	return &checker.RetryPolicy{
+		MaxAttempts:    c.Int("max-attempts"),
+		InitialBackoff: c.Duration("initial-backoff"),
+		MaxBackoff:     c.Duration("max-backoff"),
+		RetryTimeout:   c.Duration("retry-timeout"),
+		Jitter:         0.2,
+	}
+}
+
+// checkerOptionsFromFlags builds the transport-level checker.Options from
+// CLI flags.
+func checkerOptionsFromFlags(c *cli.Context) []checker.Option {
+	var opts []checker.Option
+
+	switch {
+	case c.Bool("ipv4-only"):
+		opts = append(opts, checker.WithIPv4Only())
+	case c.Bool("ipv6-only"):
+		opts = append(opts, checker.WithIPv6Only())
+	}
+
+	if c.Bool("http2-disabled") {
+		opts = append(opts, checker.WithHTTP2Disabled())
+	}
+
+	if timeout := c.Duration("request-timeout"); timeout > 0 {
+		opts = append(opts, checker.WithRequestTimeout(timeout))
+	}
+
+	opts = append(opts, checker.WithSchedulerConfig(checker.SchedulerConfig{
+		QPS:          c.Float64("qps"),
+		MinWorkers:   c.Int("workers"),
+		MaxWorkers:   c.Int("max-workers"),
+		PerHostLimit: c.Int("per-host-limit"),
+	}))
+
+	return opts
+}
+
+// CheckDomainsCommand scans domains for the presence of operate_area in
+// /__dayu/siteInfo.html (internal/pkg/app/checker).
+func CheckDomainsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "check-domains",
+		Usage: "Check domains for the presence of operate_area in /__dayu/siteInfo.html",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "input", Aliases: []string{"i"}, Usage: "Input file containing domains (one per line)", Required: true},
+			&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "JSONL output file, one CheckResult record per domain", Required: true},
+			&cli.StringFlag{Name: "successful-output", Usage: "Optional plain-text file listing only successful domains"},
+			&cli.StringFlag{Name: "proxy-file", Usage: "Optional file of proxies (one scheme://[user:pass@]host:port per line) to dial checks through, health-scored and weighted"},
+			&cli.IntFlag{Name: "workers", Aliases: []string{"w"}, Value: 10, Usage: "Minimum/starting number of concurrent workers"},
+			&cli.IntFlag{Name: "max-workers", Value: 40, Usage: "Ceiling the adaptive scheduler can grow workers to on sustained success"},
+			&cli.Float64Flag{Name: "qps", Usage: "Global rate limit in requests/second (0 disables the limit)"},
+			&cli.IntFlag{Name: "per-host-limit", Value: 5, Usage: "Max concurrent requests per host (0 disables the cap)"},
+			&cli.IntFlag{Name: "max-attempts", Value: 1, Usage: "Retry transient failures (network errors/5xx) up to this many attempts (1 disables retries)"},
+			&cli.DurationFlag{Name: "initial-backoff", Value: 500 * time.Millisecond, Usage: "Backoff before the first retry"},
+			&cli.DurationFlag{Name: "max-backoff", Value: 10 * time.Second, Usage: "Backoff ceiling between retries"},
+			&cli.DurationFlag{Name: "retry-timeout", Value: 60 * time.Second, Usage: "Cumulative retry budget per domain"},
+			&cli.DurationFlag{Name: "request-timeout", Value: 30 * time.Second, Usage: "Per-request timeout"},
+			&cli.BoolFlag{Name: "ipv4-only", Usage: "Force outbound connections over IPv4"},
+			&cli.BoolFlag{Name: "ipv6-only", Usage: "Force outbound connections over IPv6"},
+			&cli.BoolFlag{Name: "http2-disabled", Usage: "Force HTTP/1.1 for hosts whose HTTP/2 stack misbehaves"},
+			&cli.BoolFlag{Name: "no-progress", Usage: "Disable the live progress bar"},
+			&cli.BoolFlag{Name: "silent", Usage: "Suppress the live progress bar (alias of --no-progress)"},
+		},
+		Before: ApplyLogLevel,
+		Action: func(c *cli.Context) error {
+			inputFile := c.String("input")
+			outputFile := c.String("output")
+			successfulOutputFile := c.String("successful-output")
+
+			if _, err := os.Stat(inputFile); os.IsNotExist(err) {
+				return fmt.Errorf("input file does not exist: %s", inputFile)
+			}
+
+			if outputDir := filepath.Dir(outputFile); outputDir != "." {
+				if err := os.MkdirAll(outputDir, 0755); err != nil {
+					return fmt.Errorf("failed to create output directory: %w", err)
+				}
+			}
+
+			var chk *checker.Checker
+			if proxyFile := c.String("proxy-file"); proxyFile != "" {
+				proxies, err := checker.LoadProxiesFromFile(proxyFile)
+				if err != nil {
+					return fmt.Errorf("failed to load proxy file: %w", err)
+				}
+				fmt.Printf("Loaded %d proxies\n", len(proxies))
+				chk = checker.NewCheckerWithProxies(checker.NewProxyPool(proxies), c.Int("workers"), checkerOptionsFromFlags(c)...)
+			} else {
+				chk = checker.NewChecker(c.Int("workers"), checkerOptionsFromFlags(c)...)
+			}
+			chk.SetRetryPolicy(retryPolicyFromFlags(c))
+
+			domains, err := chk.ReadDomains(inputFile)
+			if err != nil {
+				return fmt.Errorf("failed to read domains: %w", err)
+			}
+
+			if len(domains) == 0 {
+				return fmt.Errorf("no domains found in input file")
+			}
+
+			fmt.Printf("Found %d domains to check\n", len(domains))
+
+			outFile, err := os.Create(outputFile)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer outFile.Close()
+
+			var successWriter *bufio.Writer
+			if successfulOutputFile != "" {
+				successFile, err := os.Create(successfulOutputFile)
+				if err != nil {
+					return fmt.Errorf("failed to create successful-output file: %w", err)
+				}
+				defer successFile.Close()
+
+				successWriter = bufio.NewWriter(successFile)
+				defer successWriter.Flush()
+			}
+
+			ctx, cancel := WithShutdown(c.Context)
+			defer cancel()
+
+			fmt.Println("Checking domains...")
+
+			var mu sync.Mutex
+			successCount := 0
+			failureCount := 0
+			var results []checker.CheckResult
+
+			err = chk.CheckDomains(ctx, domains, outFile, c.Bool("no-progress") || c.Bool("silent"), func(result checker.CheckResult) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				results = append(results, result)
+
+				if result.Success {
+					successCount++
+					if successWriter != nil {
+						successWriter.WriteString(result.Domain + "\n")
+					}
+				} else {
+					failureCount++
+					fmt.Printf("FAILED: %s - %s (attempts: %d)\n", result.Domain, result.Error, result.Attempts)
+				}
+			})
+			if err != nil {
+				return fmt.Errorf("failed to check domains: %w", err)
+			}
+
+			fmt.Println()
+			fmt.Printf("Results:\n")
+			fmt.Printf("  Total domains: %d\n", len(domains))
+			fmt.Printf("  Successful: %d\n", successCount)
+			fmt.Printf("  Failed: %d\n", failureCount)
+			fmt.Printf("  Output file: %s\n", outputFile)
+
+			clusters := chk.Cluster(results)
+			mirrored := 0
+			for _, domains := range clusters {
+				if len(domains) > 1 {
+					mirrored++
+				}
+			}
+			fmt.Printf("  Clusters: %d (%d with multiple mirrors)\n", len(clusters), mirrored)
+
+			return nil
+		},
+	}
+}
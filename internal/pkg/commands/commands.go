@@ -0,0 +1,92 @@
+// Package commands holds the urfave/cli/v2 command tree shared by the
+// consolidated dayusch binary (cmd/dayusch) and the thin per-tool shims
+// kept under cmd/batchproxy, cmd/checker, cmd/ipc, cmd/schstat and
+// cmd/cfbatch for one release. Each command wraps an existing
+// internal/pkg/app/* entry point; this package only owns flag parsing,
+// env-var binding and the shared graceful-shutdown context.
+package commands
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/charmbracelet/log"
+	"github.com/urfave/cli/v2"
+)
+
+// WithShutdown returns a context derived from parent that is cancelled on
+// SIGINT/SIGTERM, so every subcommand shuts down gracefully regardless of
+// which binary invoked it.
+func WithShutdown(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		log.Info("Received shutdown signal, stopping gracefully...")
+		cancel()
+	}()
+
+	return ctx, cancel
+}
+
+// GlobalFlags are shared across every subcommand: Mongo connection details,
+// log level and a default concurrency, all bindable via DAYU_-prefixed env
+// vars (falling back to the legacy unprefixed names already used by the
+// app packages).
+func GlobalFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "mongo-uri",
+			EnvVars: []string{"DAYU_MONGO_URI", "MONGO_URI"},
+			Usage:   "MongoDB connection URI",
+		},
+		&cli.StringFlag{
+			Name:    "mongo-db",
+			EnvVars: []string{"DAYU_MONGO_DB", "MONGO_DB"},
+			Usage:   "MongoDB database name",
+		},
+		&cli.StringFlag{
+			Name:    "log-level",
+			EnvVars: []string{"DAYU_LOG_LEVEL"},
+			Value:   "info",
+			Usage:   "Log level (debug, info, warn, error)",
+		},
+		&cli.IntFlag{
+			Name:    "concurrency",
+			EnvVars: []string{"DAYU_CONCURRENCY"},
+			Value:   10,
+			Usage:   "Default concurrency for subcommands that support it",
+		},
+	}
+}
+
+// ApplyLogLevel sets charmbracelet/log's global level from --log-level.
+// Every command's Before hook should call this.
+func ApplyLogLevel(c *cli.Context) error {
+	lvl, err := log.ParseLevel(c.String("log-level"))
+	if err != nil {
+		return err
+	}
+	log.SetLevel(lvl)
+	return nil
+}
+
+// RunStandalone runs cmd as the root command of its own binary: its flags
+// are merged with GlobalFlags so --mongo-uri/--log-level/etc. keep working,
+// and its Action runs directly instead of behind a subcommand name. This is
+// what the thin per-tool shims call.
+func RunStandalone(cmd *cli.Command, args []string) error {
+	app := &cli.App{
+		Name:   cmd.Name,
+		Usage:  cmd.Usage,
+		Flags:  append(GlobalFlags(), cmd.Flags...),
+		Before: ApplyLogLevel,
+		Action: cmd.Action,
+	}
+
+	return app.Run(args)
+}
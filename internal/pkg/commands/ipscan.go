@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"dayusch/internal/pkg/pool"
+	"dayusch/internal/pkg/progress"
+)
+
+const (
+	ipScanConcurrency = 256
+	ipScanTimeout     = 5 * time.Second
+	ipScanTotal       = 256 * 256
+)
+
+// IPScanCommand sweeps the 34.101.0.0/16 range for hosts answering with a
+// STATUS_CODES body, writing hits to results.txt.
+func IPScanCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "ipscan",
+		Usage: "Sweep 34.101.0.0/16 for hosts answering with a STATUS_CODES body",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "no-progress", Usage: "Disable the live progress bar"},
+		},
+		Before: ApplyLogLevel,
+		Action: func(c *cli.Context) error {
+			client := &http.Client{
+				Timeout: ipScanTimeout,
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				},
+			}
+
+			file, err := os.Create("results.txt")
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			writer := bufio.NewWriter(file)
+			defer writer.Flush()
+			var writerMu sync.Mutex
+
+			ctx, cancel := WithShutdown(c.Context)
+			defer cancel()
+
+			sweep, ctx := pool.New(ctx, ipScanConcurrency)
+			bar := progress.New("Sweeping IPs", ipScanTotal, progress.WithSilent(c.Bool("no-progress")))
+			sweep.OnProgress(ipScanTotal, bar.Update)
+
+			for i := 0; i < 256; i++ {
+				for j := 0; j < 256; j++ {
+					ip := fmt.Sprintf("http://34.101.%d.%d", i, j)
+					sweep.Go(func() error {
+						select {
+						case <-ctx.Done():
+							return nil
+						default:
+						}
+
+						fmt.Printf("Checking: %s\n", ip)
+						resp, err := client.Get(ip)
+						if err != nil {
+							return nil
+						}
+						defer resp.Body.Close()
+
+						buf := make([]byte, 8192)
+						n, _ := resp.Body.Read(buf)
+						if strings.Contains(string(buf[:n]), "STATUS_CODES") {
+							fmt.Println("Found on:", ip)
+							writerMu.Lock()
+							writer.WriteString(ip + "\n")
+							writer.Flush()
+							writerMu.Unlock()
+						}
+
+						return nil
+					})
+				}
+			}
+
+			sweep.Wait()
+			bar.Finish()
+
+			return nil
+		},
+	}
+}
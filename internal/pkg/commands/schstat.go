@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"dayusch/internal/pkg/app/schstat"
+)
+
+// SchStatCommand runs the periodic app-stats aggregation loop
+// (internal/pkg/app/schstat), mirroring --mongo-uri/--mongo-db into the
+// environment variables it already reads.
+func SchStatCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "schstat",
+		Usage:  "Periodically aggregate app statistics into MongoDB",
+		Before: ApplyLogLevel,
+		Action: func(c *cli.Context) error {
+			ctx, cancel := WithShutdown(c.Context)
+			defer cancel()
+
+			os.Setenv("MONGO_URI", c.String("mongo-uri"))
+			os.Setenv("MONGO_DB", c.String("mongo-db"))
+
+			schstat.NewSchStat(ctx).Run()
+			return nil
+		},
+	}
+}
@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"dayusch/internal/pkg/app/statsworker"
+)
+
+// StatsWorkerCommand runs the change-stream-driven AppStats worker
+// (internal/pkg/app/statsworker), mirroring --mongo-uri/--mongo-db into
+// the environment variables it already reads.
+func StatsWorkerCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "statsworker",
+		Usage:  "Maintain AppStats incrementally off a MongoDB change stream",
+		Before: ApplyLogLevel,
+		Action: func(c *cli.Context) error {
+			ctx, cancel := WithShutdown(c.Context)
+			defer cancel()
+
+			os.Setenv("MONGO_URI", c.String("mongo-uri"))
+			os.Setenv("MONGO_DB", c.String("mongo-db"))
+
+			statsworker.NewStatsWorker(ctx).Run()
+			return nil
+		},
+	}
+}
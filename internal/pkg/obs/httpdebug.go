@@ -0,0 +1,48 @@
+package obs
+
+import (
+	"net/http"
+
+	"github.com/imroc/req/v3"
+)
+
+// redactedHeaders lists the request headers whose value must never reach
+// a DEBUG log line verbatim.
+var redactedHeaders = []string{"x-token", "authorization"}
+
+// AttachHTTPDebugLogging wires request/response debug logging onto
+// client: every request logs its method, URL and headers (token headers
+// redacted) before it's sent, and its status after the response comes
+// back. Both are logged at DEBUG, so they're silent unless LOG_LEVEL=debug.
+func AttachHTTPDebugLogging(client *req.Client, logger *Logger) {
+	client.OnBeforeRequest(func(c *req.Client, r *req.Request) error {
+		logger.Debug(r.Context(), "http request",
+			"method", r.Method,
+			"url", r.URL.String(),
+			"headers", redactHeaders(r.Headers),
+		)
+		return nil
+	})
+
+	client.OnAfterResponse(func(c *req.Client, r *req.Response) error {
+		logger.Debug(r.Request.Context(), "http response",
+			"method", r.Request.Method,
+			"url", r.Request.URL.String(),
+			"status", r.StatusCode,
+		)
+		return nil
+	})
+}
+
+// redactHeaders returns a copy of h with every header in redactedHeaders
+// replaced by a fixed placeholder, so a logged request never leaks a
+// bearer token or API key.
+func redactHeaders(h http.Header) http.Header {
+	redacted := h.Clone()
+	for _, key := range redactedHeaders {
+		if redacted.Get(key) != "" {
+			redacted.Set(key, "[redacted]")
+		}
+	}
+	return redacted
+}
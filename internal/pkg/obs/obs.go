@@ -0,0 +1,124 @@
+// Package obs provides the structured logging shared by the API clients
+// (yarun, cfbatch) and the batch CLIs: a Logger wrapping log/slog with a
+// leveled, field-based call (Debug/Info/Warn/Error) plus an APICall helper
+// for the endpoint/latency_ms/status fields every client emits. A trace_id
+// threaded through context.Context via WithTraceID/TraceID lets a single
+// user-claim failure be correlated across the dialer, proxy selection, and
+// upstream CF batch response. It replaces the ad-hoc
+// github.com/charmbracelet/log calls that used to be scattered through
+// each of those packages.
+package obs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+type traceIDKeyType struct{}
+
+var traceIDKey traceIDKeyType
+
+// WithTraceID returns a copy of ctx carrying traceID, so every Logger call
+// made with it (or a context derived from it) tags its entry with the same
+// trace_id.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey, traceID)
+}
+
+// TraceID returns the trace_id carried by ctx, or "" if none was set.
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey).(string)
+	return id
+}
+
+// NewTraceID generates a random trace_id for WithTraceID, so a caller can
+// tag one logical operation (a round, a claim batch) without depending on
+// a UUID library. Falls back to the current time if crypto/rand fails.
+func NewTraceID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// Logger wraps an *slog.Logger, pulling trace_id out of the context passed
+// to each call so callers don't have to thread it through args by hand.
+type Logger struct {
+	slog *slog.Logger
+}
+
+// levelFromEnv reads LOG_LEVEL ("debug", "warn", "error"), defaulting to
+// info for an empty or unrecognized value.
+func levelFromEnv() slog.Level {
+	switch os.Getenv("LOG_LEVEL") {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// New creates a Logger writing leveled text lines to stderr, honoring
+// LOG_LEVEL.
+func New() *Logger {
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: levelFromEnv()})
+	return &Logger{slog: slog.New(handler)}
+}
+
+var std = New()
+
+// Default returns the package-level Logger API clients fall back to when
+// constructed without one of their own.
+func Default() *Logger {
+	return std
+}
+
+// fieldsWithTrace appends trace_id to args when ctx carries one.
+func fieldsWithTrace(ctx context.Context, args []any) []any {
+	if id := TraceID(ctx); id != "" {
+		args = append(args, "trace_id", id)
+	}
+	return args
+}
+
+func (l *Logger) Debug(ctx context.Context, msg string, args ...any) {
+	l.slog.Debug(msg, fieldsWithTrace(ctx, args)...)
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, args ...any) {
+	l.slog.Info(msg, fieldsWithTrace(ctx, args)...)
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, args ...any) {
+	l.slog.Warn(msg, fieldsWithTrace(ctx, args)...)
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, args ...any) {
+	l.slog.Error(msg, fieldsWithTrace(ctx, args)...)
+}
+
+// Fatal logs msg at ERROR and then exits the process, matching the
+// charmbracelet/log.Fatal calls this package replaces.
+func (l *Logger) Fatal(ctx context.Context, msg string, args ...any) {
+	l.slog.Error(msg, fieldsWithTrace(ctx, args)...)
+	os.Exit(1)
+}
+
+// APICall logs one completed API request with the field set shared by
+// every client call site: endpoint, how long it took, and its outcome
+// status. Extra fields specific to that call (proxy_ip, user_id, ...) are
+// passed through args as alternating key/value pairs, same as slog.
+func (l *Logger) APICall(ctx context.Context, endpoint string, latency time.Duration, status string, args ...any) {
+	fields := append([]any{"endpoint", endpoint, "latency_ms", latency.Milliseconds(), "status", status}, args...)
+	l.Info(ctx, "api call", fields...)
+}
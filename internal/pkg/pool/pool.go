@@ -0,0 +1,66 @@
+// Package pool provides a shared bounded-concurrency worker group for the
+// batch CLIs, built on golang.org/x/sync/errgroup. It replaces the
+// hand-rolled sync.WaitGroup + buffered-channel semaphore pattern that used
+// to be duplicated across batchproxy, checker and the IP sweeper: any worker
+// returning a non-nil error cancels the shared context and that error (the
+// first one) is what Wait returns.
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ProgressFunc is invoked after each job completes with the number of jobs
+// done so far out of total. total is 0 when it was not supplied to New.
+type ProgressFunc func(done, total int)
+
+// Pool runs a bounded number of jobs concurrently against a shared,
+// self-cancelling context.
+type Pool struct {
+	group    *errgroup.Group
+	total    int
+	done     int32
+	progress ProgressFunc
+}
+
+// New creates a Pool derived from parent and returns the context workers
+// should use: it is cancelled as soon as any job returns an error, or when
+// parent is done. A non-positive limit leaves concurrency unbounded.
+func New(parent context.Context, limit int) (*Pool, context.Context) {
+	group, ctx := errgroup.WithContext(parent)
+	if limit > 0 {
+		group.SetLimit(limit)
+	}
+
+	return &Pool{group: group}, ctx
+}
+
+// OnProgress registers a per-job progress callback. total is the expected
+// number of jobs, reported back on every callback invocation.
+func (p *Pool) OnProgress(total int, fn ProgressFunc) {
+	p.total = total
+	p.progress = fn
+}
+
+// Go schedules fn to run in the pool, blocking until a slot is free.
+func (p *Pool) Go(fn func() error) {
+	p.group.Go(func() error {
+		err := fn()
+
+		if p.progress != nil {
+			done := int(atomic.AddInt32(&p.done, 1))
+			p.progress(done, p.total)
+		}
+
+		return err
+	})
+}
+
+// Wait blocks until every scheduled job has returned, then returns the
+// first non-nil error, if any.
+func (p *Pool) Wait() error {
+	return p.group.Wait()
+}
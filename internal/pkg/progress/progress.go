@@ -0,0 +1,117 @@
+// Package progress renders live progress for long-running CLIs: a
+// cheggaaa/pb/v3 bar with speed and ETA when stdout is a TTY, falling back
+// to periodic charmbracelet/log structured lines otherwise. It's meant to
+// be plugged straight into dayusch/internal/pkg/pool's OnProgress hook.
+package progress
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/cheggaaa/pb/v3"
+	"golang.org/x/term"
+)
+
+// Bar reports progress for a batch of total units of work labeled label.
+// Update satisfies dayusch/internal/pkg/pool's ProgressFunc signature, so a
+// Bar can be wired in directly via Pool.OnProgress.
+type Bar struct {
+	label string
+	total int
+
+	bar *pb.ProgressBar // TTY path
+
+	done     int64 // non-TTY path, updated atomically
+	interval time.Duration
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// Option configures a Bar.
+type Option func(*Bar)
+
+// WithSilent disables all output, matching the --silent/--no-progress flags
+// used across the other CLIs in this repo. When silent, Update and Finish
+// are no-ops.
+func WithSilent(silent bool) Option {
+	return func(b *Bar) {
+		if silent {
+			b.label = ""
+		}
+	}
+}
+
+// WithLogInterval sets how often structured log lines are emitted on the
+// non-TTY fallback path. Defaults to 5s.
+func WithLogInterval(d time.Duration) Option {
+	return func(b *Bar) { b.interval = d }
+}
+
+// New creates a Bar for total units of work labeled label. Pass it to
+// Pool.OnProgress, or call Update/Finish directly.
+func New(label string, total int, opts ...Option) *Bar {
+	b := &Bar{label: label, total: total, interval: 5 * time.Second}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if b.label == "" { // WithSilent(true) cleared it
+		return b
+	}
+
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		tmpl := `{{ "` + label + `" }} {{ bar . }} {{ counters . }} {{ speed . }} {{ rtime . "ETA %s" }}`
+		b.bar = pb.ProgressBarTemplate(tmpl).Start(total)
+		return b
+	}
+
+	b.stop = make(chan struct{})
+	b.stopped = make(chan struct{})
+	go b.logLoop()
+
+	return b
+}
+
+func (b *Bar) logLoop() {
+	defer close(b.stopped)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			done := atomic.LoadInt64(&b.done)
+			log.Info(b.label, "done", done, "total", b.total)
+		}
+	}
+}
+
+// Update reports that done out of total units have completed. It matches
+// dayusch/internal/pkg/pool's ProgressFunc signature.
+func (b *Bar) Update(done, total int) {
+	if b.bar != nil {
+		b.bar.SetCurrent(int64(done))
+		return
+	}
+	if b.stop != nil {
+		atomic.StoreInt64(&b.done, int64(done))
+	}
+}
+
+// Finish stops the bar (or the log fallback goroutine). Safe to call from a
+// SIGINT handler alongside the same cancel path that stops the work itself.
+func (b *Bar) Finish() {
+	if b.bar != nil {
+		b.bar.Finish()
+		return
+	}
+	if b.stop != nil {
+		close(b.stop)
+		<-b.stopped
+	}
+}
@@ -23,6 +23,26 @@ func NewAppRepo(md *mongo.Database) *AppRepo {
 	}
 }
 
+// GetAllApps returns every app in the collection, unfiltered by
+// is_active/services.claim, for callers (like dupdel's bulk dedupe
+// pre-pass) that need to scope a query to every app_code that could
+// possibly appear on a user document rather than just claim-eligible ones.
+func (r *AppRepo) GetAllApps(ctx context.Context) ([]*ModelApp, error) {
+	cur, err := r.mc.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var apps []*ModelApp
+
+	if err = cur.All(ctx, &apps); err != nil {
+		return nil, err
+	}
+
+	return apps, nil
+}
+
 func (r *AppRepo) GetClaimAppCodes(ctx context.Context) ([]*ModelApp, error) {
 	filter := bson.M{
 		"is_active":      true,
@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ModelCursor is the persisted checkpoint for a resumable keyset-pagination
+// job such as "claim" or "dedupe". AppsHash lets callers detect that the
+// app set used to compute LastID has changed since the cursor was saved, so
+// a stale cursor doesn't silently skip users belonging to newly added apps.
+type ModelCursor struct {
+	JobName   string             `bson:"job_name" json:"job_name"`
+	LastID    primitive.ObjectID `bson:"last_id" json:"last_id"`
+	AppsHash  string             `bson:"apps_hash" json:"apps_hash"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type CursorRepo struct {
+	md *mongo.Database
+	mc *mongo.Collection
+}
+
+func NewCursorRepo(md *mongo.Database) *CursorRepo {
+	mc := md.Collection(CollectionCursors)
+
+	return &CursorRepo{
+		md: md,
+		mc: mc,
+	}
+}
+
+// LoadCursor returns the last checkpointed ObjectID for jobName, provided
+// the stored appsHash still matches. A missing cursor or an appsHash
+// mismatch (the app set changed since the checkpoint was written) both
+// resume from primitive.NilObjectID rather than erroring.
+func (r *CursorRepo) LoadCursor(ctx context.Context, jobName, appsHash string) (primitive.ObjectID, error) {
+	var cursor ModelCursor
+	err := r.mc.FindOne(ctx, bson.M{"job_name": jobName}).Decode(&cursor)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return primitive.NilObjectID, nil
+		}
+		return primitive.NilObjectID, err
+	}
+
+	if cursor.AppsHash != appsHash {
+		return primitive.NilObjectID, nil
+	}
+
+	return cursor.LastID, nil
+}
+
+// SaveCursor upserts the checkpoint for jobName with lastID and appsHash.
+func (r *CursorRepo) SaveCursor(ctx context.Context, jobName string, lastID primitive.ObjectID, appsHash string) error {
+	_, err := r.mc.UpdateOne(ctx,
+		bson.M{"job_name": jobName},
+		bson.M{"$set": bson.M{
+			"last_id":    lastID,
+			"apps_hash":  appsHash,
+			"updated_at": time.Now(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// HashAppCodes derives a stable fingerprint of an app set from their
+// AppCodes, independent of fetch order, so callers can tell whether the
+// eligible app set changed between runs and invalidate a stored cursor.
+func HashAppCodes(apps []*ModelApp) string {
+	codes := make([]string, 0, len(apps))
+	for _, app := range apps {
+		codes = append(codes, app.AppCode)
+	}
+	sort.Strings(codes)
+
+	sum := sha256.Sum256([]byte(strings.Join(codes, ",")))
+	return hex.EncodeToString(sum[:])
+}
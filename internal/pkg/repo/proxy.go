@@ -0,0 +1,52 @@
+package repo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ModelProxy is a proxy endpoint the checker package can dial through,
+// mirroring the ModelUser/ModelApp shape so it can be loaded from the same
+// kind of Mongo collection.
+type ModelProxy struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	IsActive bool               `bson:"is_active" json:"is_active"`
+	Scheme   string             `bson:"scheme" json:"scheme"` // "http", "https" or "socks5"
+	Address  string             `bson:"address" json:"address"`
+	Username string             `bson:"username,omitempty" json:"username,omitempty"`
+	Password string             `bson:"password,omitempty" json:"password,omitempty"`
+}
+
+type ProxyRepo struct {
+	md *mongo.Database
+	mc *mongo.Collection
+}
+
+func NewProxyRepo(md *mongo.Database) *ProxyRepo {
+	mc := md.Collection(CollectionProxies)
+
+	return &ProxyRepo{
+		md: md,
+		mc: mc,
+	}
+}
+
+// ListActive returns every proxy with is_active set, for callers (e.g.
+// checker.NewCheckerWithProxies) that build a pool once at startup.
+func (r *ProxyRepo) ListActive(ctx context.Context) ([]*ModelProxy, error) {
+	cursor, err := r.mc.Find(ctx, bson.M{"is_active": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var proxies []*ModelProxy
+	if err := cursor.All(ctx, &proxies); err != nil {
+		return nil, err
+	}
+
+	return proxies, nil
+}
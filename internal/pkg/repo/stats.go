@@ -0,0 +1,148 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AppConfig is the subset of the apps collection statsworker needs to
+// classify a user as playable (balance inside the app's game range).
+// ModelApp doesn't carry game_min_balance/game_max_balance since nothing
+// else in Go reads them today - AggregateAppStats' $lookup pipeline talks
+// to the raw BSON document instead - so this decodes them directly.
+type AppConfig struct {
+	AppCode        string  `bson:"app_code"`
+	GameMinBalance float64 `bson:"game_min_balance"`
+	GameMaxBalance float64 `bson:"game_max_balance"`
+}
+
+// ListAppConfigs returns the balance range of every active app.
+func (r *AppRepo) ListAppConfigs(ctx context.Context) ([]AppConfig, error) {
+	cur, err := r.mc.Find(ctx, bson.M{"is_active": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var configs []AppConfig
+	if err := cur.All(ctx, &configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// RecentUser is one entry of an AppStats *_list field.
+type RecentUser struct {
+	Username    string    `bson:"username"`
+	Balance     float64   `bson:"balance"`
+	Coin        float64   `bson:"coin"`
+	LastCheckAt time.Time `bson:"last_check_at"`
+}
+
+// AppStatsCounts is the incrementally-maintained half of an AppStats
+// document - everything statsworker can recompute from the change stream
+// without rescanning the users collection. first_*/inc_* stay untouched
+// here; only AggregateAppStats' daily reseed recomputes those.
+type AppStatsCounts struct {
+	ValidUsersCount      int          `bson:"valid_users_count"`
+	PlayableUsersCount   int          `bson:"playable_users_count"`
+	JackpotUsersCount    int          `bson:"jackpot_users_count"`
+	ProcessedUsersCount  int          `bson:"processed_users_count"`
+	LatestUsersCheckList []RecentUser `bson:"latest_users_check_list"`
+	PlayableUsersList    []RecentUser `bson:"playable_users_list"`
+	JackpotUsersList     []RecentUser `bson:"jackpot_users_list"`
+}
+
+// StatsRepo persists statsworker's incremental AppStats flushes and its
+// change stream resume token, as a sibling to AppRepo's own
+// AggregateAppStats reseed path.
+type StatsRepo struct {
+	md         *mongo.Database
+	statsColl  *mongo.Collection
+	resumeColl *mongo.Collection
+}
+
+func NewStatsRepo(md *mongo.Database) *StatsRepo {
+	return &StatsRepo{
+		md:         md,
+		statsColl:  md.Collection(CollectionAppStats),
+		resumeColl: md.Collection(CollectionStatsResumeTokens),
+	}
+}
+
+// FlushAppStats upserts the incremental counters and lists for (appCode,
+// date) computed so far, without touching first_*/inc_* - those are only
+// ever written by AggregateAppStats.
+func (r *StatsRepo) FlushAppStats(ctx context.Context, appCode, date string, counts AppStatsCounts) error {
+	_, err := r.statsColl.UpdateOne(ctx,
+		bson.M{"app_code": appCode, "date": date},
+		bson.M{
+			"$set": bson.M{
+				"valid_users_count":       counts.ValidUsersCount,
+				"playable_users_count":    counts.PlayableUsersCount,
+				"jackpot_users_count":     counts.JackpotUsersCount,
+				"processed_users_count":   counts.ProcessedUsersCount,
+				"latest_users_check_list": counts.LatestUsersCheckList,
+				"playable_users_list":     counts.PlayableUsersList,
+				"jackpot_users_list":      counts.JackpotUsersList,
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetAppStatsCounts reads back the counters written for (appCode, date),
+// so the daily reseed can resync in-memory state to whatever
+// AggregateAppStats just reconciled.
+func (r *StatsRepo) GetAppStatsCounts(ctx context.Context, appCode, date string) (AppStatsCounts, error) {
+	var doc AppStatsCounts
+	err := r.statsColl.FindOne(ctx, bson.M{"app_code": appCode, "date": date}).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return AppStatsCounts{}, nil
+		}
+		return AppStatsCounts{}, err
+	}
+	return doc, nil
+}
+
+// ModelStatsResumeToken persists the change stream resume token for a
+// named statsworker job, so a restart resumes instead of replaying from
+// the start of the oplog (or missing events entirely).
+type ModelStatsResumeToken struct {
+	JobName     string    `bson:"job_name"`
+	ResumeToken bson.Raw  `bson:"resume_token"`
+	UpdatedAt   time.Time `bson:"updated_at"`
+}
+
+// LoadResumeToken returns the last persisted resume token for jobName, or
+// nil if none is stored yet.
+func (r *StatsRepo) LoadResumeToken(ctx context.Context, jobName string) (bson.Raw, error) {
+	var doc ModelStatsResumeToken
+	err := r.resumeColl.FindOne(ctx, bson.M{"job_name": jobName}).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return doc.ResumeToken, nil
+}
+
+// SaveResumeToken upserts the resume token for jobName.
+func (r *StatsRepo) SaveResumeToken(ctx context.Context, jobName string, token bson.Raw) error {
+	_, err := r.resumeColl.UpdateOne(ctx,
+		bson.M{"job_name": jobName},
+		bson.M{"$set": bson.M{
+			"resume_token": token,
+			"updated_at":   time.Now(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
@@ -7,8 +7,14 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// defaultBulkWriteBatchSize is how many write models are gathered before
+// they're flushed in one BulkWrite call, so a large duplicate set or result
+// batch doesn't build one unbounded in-memory write list.
+const defaultBulkWriteBatchSize = 1000
+
 type UserRepo struct {
 	md *mongo.Database
 	mc *mongo.Collection
@@ -169,3 +175,110 @@ func (r *UserRepo) DeleteBulk(ctx context.Context, ids []string) error {
 	_, err := r.mc.DeleteMany(ctx, filter)
 	return err
 }
+
+// BulkDeduplicateResult summarizes the outcome of BulkDeduplicate across all
+// dispatched batches.
+type BulkDeduplicateResult struct {
+	Batches      int
+	DeletedCount int64
+}
+
+// BulkDeduplicate streams duplicate (app_code, username) groups across apps
+// from a single aggregation cursor and, for each group, deletes every
+// duplicate but the earliest survivor and touches the survivor's
+// last_check_at, so callers don't have to round-trip hex IDs through
+// FindDuplicate/DeleteBulk one group at a time. Writes are dispatched
+// through mongo.Collection.BulkWrite in ordered batches, flushed once at
+// least batchSize writes are queued; a group's writes are never split, so a
+// batch can run over batchSize by one group (a non-positive batchSize falls
+// back to defaultBulkWriteBatchSize).
+func (r *UserRepo) BulkDeduplicate(ctx context.Context, apps []*ModelApp, batchSize int) (BulkDeduplicateResult, error) {
+	if batchSize <= 0 {
+		batchSize = defaultBulkWriteBatchSize
+	}
+
+	var appCodes []string
+	for _, app := range apps {
+		appCodes = append(appCodes, app.AppCode)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"app_code": bson.M{"$in": appCodes}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "app_code", Value: "$app_code"},
+				{Key: "username", Value: "$username"},
+			}},
+			{Key: "survivor", Value: bson.D{{Key: "$first", Value: "$_id"}}},
+			{Key: "ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "survivor", Value: 1},
+			{Key: "duplicates", Value: bson.D{
+				{Key: "$slice", Value: bson.A{"$ids", 1, bson.D{{Key: "$subtract", Value: bson.A{"$count", 1}}}}},
+			}},
+		}}},
+	}
+
+	cursor, err := r.mc.Aggregate(ctx, pipeline)
+	if err != nil {
+		return BulkDeduplicateResult{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var result BulkDeduplicateResult
+	now := time.Now()
+	models := make([]mongo.WriteModel, 0, batchSize)
+
+	flush := func() error {
+		if len(models) == 0 {
+			return nil
+		}
+
+		res, err := r.mc.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(true))
+		models = models[:0]
+		if err != nil {
+			return err
+		}
+
+		result.Batches++
+		result.DeletedCount += res.DeletedCount
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var group struct {
+			Survivor   primitive.ObjectID   `bson:"survivor"`
+			Duplicates []primitive.ObjectID `bson:"duplicates"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return result, err
+		}
+
+		for _, id := range group.Duplicates {
+			models = append(models, mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": id}))
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": group.Survivor}).
+			SetUpdate(bson.M{"$set": bson.M{"last_check_at": now}}))
+
+		if len(models) >= batchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return result, err
+	}
+
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
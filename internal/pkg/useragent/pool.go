@@ -0,0 +1,126 @@
+package useragent
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+)
+
+// SelectionStrategy decides which UserAgent UserAgentPool.Next hands back.
+type SelectionStrategy int
+
+const (
+	// Sequential rotates through the pool in order, wrapping at the end.
+	Sequential SelectionStrategy = iota
+	// Random draws a uniformly random entry on every call.
+	Random
+	// WeightedByOS draws an OS proportionally to how often it appears in
+	// the pool, then a random entry within that OS, so each OS comes up
+	// at its share of the pool and every entry is equally likely overall.
+	WeightedByOS
+)
+
+// UserAgentPool is a queryable, filterable set of parsed UserAgents with a
+// pluggable selection strategy, replacing a flat string slice plus a single
+// round-robin index.
+type UserAgentPool struct {
+	mu       sync.Mutex
+	agents   []UserAgent
+	strategy SelectionStrategy
+	index    int
+	rng      *rand.Rand
+}
+
+// NewUserAgentPool builds a pool from already-parsed user agents.
+func NewUserAgentPool(agents []UserAgent, strategy SelectionStrategy) *UserAgentPool {
+	return &UserAgentPool{
+		agents:   agents,
+		strategy: strategy,
+		rng:      rand.New(rand.NewSource(1)),
+	}
+}
+
+// NewUserAgentPoolFromStrings parses raw User-Agent strings into a pool.
+func NewUserAgentPoolFromStrings(raw []string, strategy SelectionStrategy) *UserAgentPool {
+	agents := make([]UserAgent, 0, len(raw))
+	for _, r := range raw {
+		agents = append(agents, ParseUserAgent(r))
+	}
+	return NewUserAgentPool(agents, strategy)
+}
+
+// Len returns the number of agents currently in the pool.
+func (p *UserAgentPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.agents)
+}
+
+// Next draws the next UserAgent according to the pool's strategy. It
+// panics if the pool is empty, same as indexing an empty slice would.
+func (p *UserAgentPool) Next() UserAgent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.strategy {
+	case Random:
+		return p.agents[p.rng.Intn(len(p.agents))]
+	case WeightedByOS:
+		return p.weightedByOSLocked()
+	default:
+		ua := p.agents[p.index]
+		p.index = (p.index + 1) % len(p.agents)
+		return ua
+	}
+}
+
+func (p *UserAgentPool) weightedByOSLocked() UserAgent {
+	byOS := make(map[string][]UserAgent)
+	var osOrder []string
+	for _, ua := range p.agents {
+		if _, ok := byOS[ua.OS]; !ok {
+			osOrder = append(osOrder, ua.OS)
+		}
+		byOS[ua.OS] = append(byOS[ua.OS], ua)
+	}
+
+	pick := p.rng.Intn(len(p.agents))
+	for _, os := range osOrder {
+		group := byOS[os]
+		if pick < len(group) {
+			return group[p.rng.Intn(len(group))]
+		}
+		pick -= len(group)
+	}
+
+	return p.agents[0]
+}
+
+// StickyPerKey deterministically maps key (e.g. an account username or
+// proxy address) to the same UserAgent on every call, so a given
+// account/proxy keeps a consistent fingerprint across requests.
+func (p *UserAgentPool) StickyPerKey(key string) UserAgent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return p.agents[int(h.Sum32())%len(p.agents)]
+}
+
+// PoolFilter returns a new pool containing only the agents matching pred,
+// keeping the parent pool's strategy. It restricts rotation to a subset,
+// e.g. iOS-only or Android-14+, without mutating the original pool.
+func (p *UserAgentPool) PoolFilter(pred func(UserAgent) bool) *UserAgentPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	filtered := make([]UserAgent, 0, len(p.agents))
+	for _, ua := range p.agents {
+		if pred(ua) {
+			filtered = append(filtered, ua)
+		}
+	}
+
+	return NewUserAgentPool(filtered, p.strategy)
+}
@@ -0,0 +1,103 @@
+package useragent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// UserAgent is a structured breakdown of a raw User-Agent string, parsed
+// well enough to drive rotation policy (e.g. "iOS only" or "Android 14+")
+// without every caller re-deriving that from the raw string itself.
+type UserAgent struct {
+	Raw       string
+	Family    string // browser/client family, e.g. "Chrome", "Safari", "Firefox"
+	Version   string // browser/client version as written, e.g. "132.0.6834.163"
+	Engine    string // rendering engine, e.g. "WebKit", "Gecko", "Blink"
+	OS        string // "Android", "iOS", "Mac OS X", ...
+	OSVersion string // e.g. "15", "18.3.2"
+	Device    string // model token when present, e.g. "SM-S931B", "Pixel 9 Pro"
+	IsMobile  bool
+	IsTablet  bool
+	IsBot     bool
+}
+
+var (
+	familyPatterns = []struct {
+		family string
+		re     *regexp.Regexp
+	}{
+		{"Edge", regexp.MustCompile(`(?i)\bEdg(e|A|iOS)?/([\d.]+)`)},
+		{"Chrome", regexp.MustCompile(`(?i)\b(?:Chrome|CriOS|CrMo)/([\d.]+)`)},
+		{"Firefox", regexp.MustCompile(`(?i)\b(?:Firefox|FxiOS)/([\d.]+)`)},
+		{"Safari", regexp.MustCompile(`(?i)\bVersion/([\d.]+).*Safari`)},
+		{"IE", regexp.MustCompile(`(?i)\b(?:MSIE |Trident/.*; rv:)([\d.]+)`)},
+	}
+
+	botPattern = regexp.MustCompile(`(?i)bot|crawler|spider|slurp|bingpreview`)
+
+	androidPattern  = regexp.MustCompile(`Android ([\d.]+)`)
+	iosPattern      = regexp.MustCompile(`CPU (?:iPhone )?OS ([\d_]+) like Mac OS X`)
+	macPattern      = regexp.MustCompile(`Mac OS X ([\d_]+)`)
+	windowsPattern  = regexp.MustCompile(`Windows NT ([\d.]+)`)
+	tabletPattern   = regexp.MustCompile(`(?i)\b(?:iPad|Tablet|SM-T\d)`)
+	androidDevice   = regexp.MustCompile(`Android [\d.]+; ([^;)]+)`)
+	iphoneDevice    = regexp.MustCompile(`(iPhone\d+,\d+)`)
+	engineByPattern = map[string]string{
+		"Chrome":  "Blink",
+		"Edge":    "Blink",
+		"Firefox": "Gecko",
+		"Safari":  "WebKit",
+		"IE":      "Trident",
+	}
+)
+
+// ParseUserAgent extracts a best-effort UserAgent from a raw header value.
+// It's a lightweight, regex-based parser in the style of ua-parser/bowser:
+// cheap enough to run on every rotation without a full grammar, accurate
+// enough to drive device-aware filtering and weighting.
+func ParseUserAgent(raw string) UserAgent {
+	ua := UserAgent{Raw: raw, IsBot: botPattern.MatchString(raw)}
+
+	for _, fp := range familyPatterns {
+		m := fp.re.FindStringSubmatch(raw)
+		if m == nil {
+			continue
+		}
+		ua.Family = fp.family
+		ua.Version = m[len(m)-1]
+		ua.Engine = engineByPattern[fp.family]
+		break
+	}
+
+	switch {
+	case androidPattern.MatchString(raw):
+		ua.OS = "Android"
+		ua.OSVersion = androidPattern.FindStringSubmatch(raw)[1]
+		ua.IsMobile = true
+		if m := androidDevice.FindStringSubmatch(raw); m != nil {
+			ua.Device = strings.TrimSpace(strings.SplitN(m[1], " Build/", 2)[0])
+		}
+	case iosPattern.MatchString(raw):
+		ua.OS = "iOS"
+		ua.OSVersion = strings.ReplaceAll(iosPattern.FindStringSubmatch(raw)[1], "_", ".")
+		ua.IsMobile = true
+		if m := iphoneDevice.FindStringSubmatch(raw); m != nil {
+			ua.Device = m[1]
+		} else {
+			ua.Device = "iPhone"
+		}
+	case macPattern.MatchString(raw):
+		ua.OS = "Mac OS X"
+		ua.OSVersion = strings.ReplaceAll(macPattern.FindStringSubmatch(raw)[1], "_", ".")
+	case windowsPattern.MatchString(raw):
+		ua.OS = "Windows"
+		ua.OSVersion = windowsPattern.FindStringSubmatch(raw)[1]
+	}
+
+	if tabletPattern.MatchString(raw) {
+		ua.IsTablet = true
+		ua.IsMobile = false
+	}
+
+	return ua
+}
@@ -0,0 +1,71 @@
+// Package vectors loads JSON conformance vectors: a canned HTTP
+// request/response pair plus the struct an API client is expected to
+// decode it to, written as that struct's own JSON encoding (keyed by its
+// json tags). It's the shared loader behind the yarun and
+// cfbatch_v2 conformance test harnesses, borrowed from the Filecoin
+// test-vectors pattern (one corpus, replayed against an httptest.Server,
+// diffed against a golden struct) so a silent upstream rename (`_id` vs
+// `id`, `r.b` vs `r.balance`) shows up as a test failure instead of a
+// quietly-zeroed field.
+package vectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Vector is one canned API call. ResponseBody is replayed verbatim by the
+// test server for a request matching Method/Path; Expect is the decoded
+// result, as JSON, that the client's return value must match.
+type Vector struct {
+	Name           string          `json:"name"`
+	Method         string          `json:"method"`
+	Path           string          `json:"path"`
+	RequestBody    json.RawMessage `json:"request_body,omitempty"`
+	ResponseStatus int             `json:"response_status"`
+	ResponseBody   json.RawMessage `json:"response_body"`
+	Expect         json.RawMessage `json:"expect"`
+}
+
+// Load reads every *.json file in dir as a Vector, in directory order.
+func Load(dir string) ([]Vector, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read vectors dir %s: %w", dir, err)
+	}
+
+	var out []Vector
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read vector %s: %w", entry.Name(), err)
+		}
+
+		var v Vector
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("parse vector %s: %w", entry.Name(), err)
+		}
+		if v.Name == "" {
+			v.Name = entry.Name()
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// Save writes v back to path as indented JSON, for a -update run that
+// re-records Expect against a live endpoint.
+func Save(path string, v Vector) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal vector: %w", err)
+	}
+	data = append(data, '\n')
+	return os.WriteFile(path, data, 0o644)
+}